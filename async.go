@@ -0,0 +1,281 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy decides what an async Logger does when its buffer is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes Log wait until there is room in the buffer, for
+	// at most BlockTimeout if set, then drop the message
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop makes Log drop the message and return ErrBufferFull
+	OverflowDrop
+	// OverflowDropOldest drops the oldest buffered message to make room
+	OverflowDropOldest
+)
+
+var (
+	// ErrBufferFull is returned by Log when the async buffer is full and
+	// the message was dropped. It is also passed to OnError with every
+	// message dropped by a full async or reconnect buffer.
+	ErrBufferFull = errors.New("graylog: buffer full, message dropped")
+	// ErrClosed is returned by Log after the Logger has been closed, and
+	// by Log calls still waiting for room in the async buffer at Close
+	ErrClosed = errors.New("graylog: logger is closed")
+)
+
+// defaultFlushInterval bounds how long a partial batch waits when
+// FlushInterval is not set
+const defaultFlushInterval = time.Second
+
+// asyncWorker ships payloads to Graylog from a background goroutine
+type asyncWorker struct {
+	queue    chan []byte
+	policy   OverflowPolicy
+	flushReq chan struct{} // asks run to send a partial batch now
+	done     chan struct{}
+
+	// ctx is passed to every send; close cancels it once its own context
+	// ends so the backlog fails fast instead of blocking shutdown
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// closeMu is held for reading while enqueueing so Close can't close
+	// the queue under a sender. closing is closed first, so senders blocked
+	// on a full queue give up and release it.
+	closeMu   sync.RWMutex
+	closed    bool
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	// pending counts payloads enqueued but not yet sent; idle, if set, is
+	// closed when it drops to zero
+	mu      sync.Mutex
+	pending int
+	idle    chan struct{}
+}
+
+func newAsyncWorker(bufferSize int, policy OverflowPolicy) *asyncWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &asyncWorker{
+		queue:    make(chan []byte, bufferSize),
+		policy:   policy,
+		flushReq: make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		closing:  make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	return w
+}
+
+// run sends queued payloads until the queue is closed. With a batchSize
+// above one, up to batchSize payloads are joined by delimiter and sent in
+// one write; a partial batch is sent after flushInterval, on flush and on
+// close.
+func (w *asyncWorker) run(send func(ctx context.Context, payload []byte, messages int), batchSize int, flushInterval time.Duration, delimiter byte) {
+	defer close(w.done)
+
+	if batchSize <= 1 {
+		for payload := range w.queue {
+			send(w.ctx, payload, 1)
+			w.finish()
+		}
+		return
+	}
+
+	batch := make([][]byte, 0, batchSize)
+	sendBatch := func() {
+		if len(batch) == 0 {
+			return
+		}
+		send(w.ctx, bytes.Join(batch, []byte{delimiter}), len(batch))
+		for range batch {
+			w.finish()
+		}
+		batch = batch[:0]
+	}
+
+	var tick <-chan time.Time
+	if flushInterval > 0 {
+		timer := time.NewTimer(flushJitter(flushInterval))
+		defer timer.Stop()
+		tick = timer.C
+	}
+
+	for {
+		select {
+		case payload, ok := <-w.queue:
+			if !ok {
+				sendBatch()
+				return
+			}
+			batch = append(batch, payload)
+			if len(batch) >= batchSize {
+				sendBatch()
+			}
+		case <-tick:
+			sendBatch()
+			tick = time.After(flushJitter(flushInterval))
+		case <-w.flushReq:
+			// Send everything already queued without waiting, in full
+			// batches and then the rest
+			for len(w.queue) > 0 {
+				batch = append(batch, <-w.queue)
+				if len(batch) >= batchSize {
+					sendBatch()
+				}
+			}
+			sendBatch()
+		}
+	}
+}
+
+// flushJitter returns a flush delay of up to a tenth less than interval, so
+// loggers started together don't all flush at once, and nothing waits
+// longer than interval
+func flushJitter(interval time.Duration) time.Duration {
+	return interval - rand.N(interval/10+1)
+}
+
+// enqueue hands payload to the async worker and reports the messages a
+// full buffer made it drop
+func (l *Logger) enqueue(ctx context.Context, payload []byte) error {
+	evicted, err := l.async.enqueue(ctx, payload, l.BlockTimeout)
+	for _, p := range evicted {
+		l.dropBuffered(bufferedPayload{p, 1})
+	}
+	if errors.Is(err, ErrBufferFull) {
+		l.dropBuffered(bufferedPayload{payload, 1})
+	}
+	return err
+}
+
+// enqueue hands payload to the worker, applying the overflow policy. It
+// returns the older payloads evicted to make room with OverflowDropOldest.
+func (w *asyncWorker) enqueue(ctx context.Context, payload []byte, blockTimeout time.Duration) (evicted [][]byte, err error) {
+	w.closeMu.RLock()
+	defer w.closeMu.RUnlock()
+	if w.closed {
+		return nil, ErrClosed
+	}
+
+	w.mu.Lock()
+	w.pending++
+	w.mu.Unlock()
+
+	switch w.policy {
+	case OverflowBlock:
+		var timeout <-chan time.Time
+		if blockTimeout > 0 {
+			timer := time.NewTimer(blockTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case w.queue <- payload:
+			return nil, nil
+		case <-timeout:
+			w.finish()
+			return nil, ErrBufferFull
+		case <-ctx.Done():
+			w.finish()
+			return nil, ctx.Err()
+		case <-w.closing:
+			w.finish()
+			return nil, ErrClosed
+		}
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case w.queue <- payload:
+				return evicted, nil
+			default:
+			}
+			// Other senders may refill the slot, so this can take a few
+			// turns
+			select {
+			case oldest := <-w.queue:
+				w.finish()
+				evicted = append(evicted, oldest)
+			default:
+			}
+		}
+	}
+
+	select {
+	case w.queue <- payload:
+		return nil, nil
+	default:
+		w.finish()
+		return nil, ErrBufferFull
+	}
+}
+
+// finish marks one pending payload as finished
+func (w *asyncWorker) finish() {
+	w.mu.Lock()
+	w.pending--
+	if w.pending == 0 && w.idle != nil {
+		close(w.idle)
+		w.idle = nil
+	}
+	w.mu.Unlock()
+}
+
+// flush blocks until every enqueued payload has been sent or ctx is done
+func (w *asyncWorker) flush(ctx context.Context) error {
+	select {
+	case w.flushReq <- struct{}{}:
+	default: // a request is already pending
+	}
+
+	w.mu.Lock()
+	if w.pending == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	if w.idle == nil {
+		w.idle = make(chan struct{})
+	}
+	idle := w.idle
+	w.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops accepting payloads and waits for the queue to drain. If ctx
+// ends first, the sends still in flight or queued are cancelled, so they
+// fail, and it returns ctx's error once the worker has stopped.
+func (w *asyncWorker) close(ctx context.Context) error {
+	w.closeOnce.Do(func() { close(w.closing) })
+	w.closeMu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.closeMu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+		return ctx.Err()
+	}
+}
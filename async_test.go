@@ -0,0 +1,310 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dryRunFrames collects the frames of a DryRun Logger
+type dryRunFrames struct {
+	mu     sync.Mutex
+	frames [][]byte
+}
+
+func (f *dryRunFrames) record(_, _ string, frames [][]byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.frames = append(f.frames, frames...)
+}
+
+func (f *dryRunFrames) get() [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]byte(nil), f.frames...)
+}
+
+func TestAsyncBufferSize(t *testing.T) {
+	tests := []struct {
+		size    int
+		policy  OverflowPolicy
+		wantErr bool
+	}{
+		{-1, OverflowBlock, true},
+		{0, OverflowDrop, true},
+		{0, OverflowDropOldest, true},
+		{1, OverflowBlock, false},
+		{1, OverflowDrop, false},
+		{1, OverflowDropOldest, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d", tt.size, tt.policy), func(t *testing.T) {
+			l, err := NewLoggerWithOptions(
+				WithGraylog("graylog", "12201", "tcp"),
+				WithDryRun(nil),
+				WithAsync(tt.size, tt.policy),
+				WithDisabledIPLookup(),
+				WithDisabledLocal(),
+			)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("no error for buffer size %d", tt.size)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			if err := l.Info("hello", LogData{}); err != nil {
+				t.Errorf("Info = %v", err)
+			}
+		})
+	}
+}
+
+func TestCloseContextBlockedSenders(t *testing.T) {
+	// Graylog never reads, so the worker blocks on its first write, the
+	// queue fills and the remaining senders wait for room
+	var mu sync.Mutex
+	var peers []net.Conn
+	dial := func(context.Context, string, string) (net.Conn, error) {
+		client, server := net.Pipe()
+		mu.Lock()
+		peers = append(peers, server)
+		mu.Unlock()
+		return client, nil
+	}
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, peer := range peers {
+			peer.Close()
+		}
+	}()
+
+	l, err := NewLoggerWithOptions(
+		WithGraylog("graylog", "12201", "tcp"),
+		WithDialFunc(dial),
+		WithAsync(1, OverflowBlock),
+		WithDisabledIPLookup(),
+		WithDisabledLocal(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const senders = 4
+	l.Info("in flight", LogData{})
+	l.Info("queued", LogData{})
+	errs := make(chan error, senders)
+	for range senders {
+		go func() { errs <- l.Info("blocked", LogData{}) }()
+	}
+	time.Sleep(50 * time.Millisecond) // let the senders block
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.CloseContext(ctx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CloseContext = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CloseContext took %v with a 100ms deadline", elapsed)
+	}
+	for range senders {
+		if err := <-errs; !errors.Is(err, ErrClosed) {
+			t.Errorf("blocked Info = %v, want %v", err, ErrClosed)
+		}
+	}
+}
+
+func TestFlushSendsEveryQueuedBatch(t *testing.T) {
+	for range 20 {
+		frames := &dryRunFrames{}
+		l, err := NewLoggerWithOptions(
+			WithGraylog("graylog", "12201", "tcp"),
+			WithDryRun(frames.record),
+			WithAsync(64, OverflowBlock),
+			WithBatching(3, time.Hour),
+			WithDisabledIPLookup(),
+			WithDisabledLocal(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range 10 {
+			l.Info(fmt.Sprintf("m%d", i), LogData{})
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err = l.Flush(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("Flush = %v with %d frames sent", err, len(frames.get()))
+		}
+		var messages int
+		for _, frame := range frames.get() {
+			messages += bytes.Count(frame, []byte{'\n'})
+		}
+		if messages != 10 {
+			t.Fatalf("%d messages sent, want 10", messages)
+		}
+		l.Close()
+	}
+}
+
+func TestAsyncBatchFraming(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		messages  int
+		delimiter byte
+	}{
+		{"full batch", nil, 3, '\n'},
+		{"partial batch", nil, 2, '\n'},
+		{"full batch, null", []Option{WithNullDelimiter()}, 3, 0},
+		{"partial batch, null", []Option{WithNullDelimiter()}, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := &dryRunFrames{}
+			opts := append([]Option{
+				WithGraylog("graylog", "12201", "tcp"),
+				WithDryRun(frames.record),
+				WithAsync(16, OverflowBlock),
+				WithBatching(3, time.Hour),
+				WithDisabledIPLookup(),
+				WithDisabledLocal(),
+			}, tt.opts...)
+			l, err := NewLoggerWithOptions(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			var want []string
+			for i := range tt.messages {
+				want = append(want, fmt.Sprintf("m%d", i))
+				l.Info(want[i], LogData{})
+			}
+			if err := l.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			got := frames.get()
+			if len(got) != 1 {
+				t.Fatalf("%d frames, want one batch", len(got))
+			}
+			frame := got[0]
+			if frame[len(frame)-1] != tt.delimiter {
+				t.Errorf("frame ends in %q, want %q", frame[len(frame)-1], tt.delimiter)
+			}
+			var sent []string
+			for _, doc := range bytes.Split(frame[:len(frame)-1], []byte{tt.delimiter}) {
+				sent = append(sent, shortMessage(doc))
+			}
+			if !slices.Equal(sent, want) {
+				t.Errorf("batch holds %q, want %q", sent, want)
+			}
+		})
+	}
+}
+
+func TestAsyncFlushInterval(t *testing.T) {
+	frames := &dryRunFrames{}
+	l, err := NewLoggerWithOptions(
+		WithGraylog("graylog", "12201", "tcp"),
+		WithDryRun(frames.record),
+		WithAsync(16, OverflowBlock),
+		WithBatching(10, 20*time.Millisecond),
+		WithDisabledIPLookup(),
+		WithDisabledLocal(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	l.Info("m0", LogData{})
+	l.Info("m1", LogData{})
+	deadline := time.Now().Add(5 * time.Second)
+	for len(frames.get()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("partial batch not sent after FlushInterval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := bytes.Count(frames.get()[0], []byte{'\n'}); n != 2 {
+		t.Errorf("batch of %d messages, want 2", n)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	tests := []struct {
+		name  string
+		async bool
+		hold  bool // Graylog doesn't read until the first Flush times out
+	}{
+		{"sync", false, false},
+		{"async", true, false},
+		{"async, Graylog stalled", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graylog := &testGraylog{}
+			if tt.hold {
+				graylog.hold = make(chan struct{})
+			}
+			opts := []Option{
+				WithGraylog("graylog", "12201", "tcp"),
+				WithDialFunc(graylog.dial),
+				WithDisabledIPLookup(),
+				WithDisabledLocal(),
+			}
+			if tt.async {
+				opts = append(opts, WithAsync(16, OverflowBlock))
+			}
+			l, err := NewLoggerWithOptions(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			if !tt.async {
+				// Sync sends finish in Log, so nothing is left to flush
+				if err := l.Flush(context.Background()); err != nil {
+					t.Errorf("Flush = %v, want nil", err)
+				}
+				return
+			}
+
+			for i := range 3 {
+				l.Info(fmt.Sprintf("m%d", i), LogData{})
+			}
+			if tt.hold {
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				err := l.Flush(ctx)
+				cancel()
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Flush while stalled = %v, want %v", err, context.DeadlineExceeded)
+				}
+				close(graylog.hold)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := l.Flush(ctx); err != nil {
+				t.Fatalf("Flush = %v, want nil", err)
+			}
+			if got := graylog.wait(t, 3); !slices.Equal(got, []string{"m0", "m1", "m2"}) {
+				t.Errorf("sent %q, want m0 to m2 in order", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultBreakerCooldown is how long an open circuit fast-fails if
+// BreakerCooldown is unset
+const defaultBreakerCooldown = 30 * time.Second
+
+// ErrCircuitOpen is returned for sends skipped because the circuit breaker
+// is open after repeated failures
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// breaker is a circuit breaker for one endpoint. After threshold
+// consecutive failed sends it opens and fails sends at once until the
+// cooldown has passed, then lets a single probe through: success closes it,
+// failure opens it again.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a send may go ahead
+func (b *breaker) allow(threshold int) bool {
+	if threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < threshold {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true // half-open
+	return true
+}
+
+// record counts the outcome of a send that allow let through
+func (b *breaker) record(threshold int, cooldown time.Duration, ok bool) {
+	if threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= threshold {
+		if cooldown <= 0 {
+			cooldown = defaultBreakerCooldown
+		}
+		b.openUntil = time.Now().Add(cooldown)
+	}
+}
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	// Each step is "allow" or "deny", checking allow, "fail" or "ok",
+	// recording a send, or "wait" for the cooldown to pass
+	tests := []struct {
+		name      string
+		threshold int
+		steps     []string
+	}{
+		{"disabled", 0, []string{"fail", "fail", "fail", "allow"}},
+		{"closed below threshold", 3, []string{"allow", "fail", "allow", "fail", "allow"}},
+		{"opens at threshold", 3, []string{"fail", "fail", "fail", "deny", "deny"}},
+		{"success resets the count", 3, []string{"fail", "fail", "ok", "fail", "fail", "allow"}},
+		{"single probe when half-open", 2, []string{"fail", "fail", "deny", "wait", "allow", "deny"}},
+		{"probe success closes", 2, []string{"fail", "fail", "wait", "allow", "ok", "allow", "allow", "fail", "allow"}},
+		{"probe failure reopens", 2, []string{"fail", "fail", "wait", "allow", "fail", "deny", "wait", "allow"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b breaker
+			for i, step := range tt.steps {
+				switch step {
+				case "allow", "deny":
+					if got := b.allow(tt.threshold); got != (step == "allow") {
+						t.Fatalf("step %d: allow = %v, want %v", i, got, !got)
+					}
+				case "ok", "fail":
+					b.record(tt.threshold, cooldown, step == "ok")
+				case "wait":
+					time.Sleep(cooldown + 5*time.Millisecond)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerFastFails(t *testing.T) {
+	graylog := &testGraylog{}
+	graylog.down.Store(true)
+	var dials atomic.Int32
+	l, err := NewLoggerWithOptions(
+		WithGraylog("graylog", "12201", "tcp"),
+		WithDialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			dials.Add(1)
+			return graylog.dial(ctx, network, address)
+		}),
+		WithDisabledIPLookup(),
+		WithDisabledLocal(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.BreakerThreshold = 2
+	l.BreakerCooldown = time.Minute
+
+	var opened int32
+	for i := range 4 {
+		err := l.Info("down", LogData{})
+		if wantOpen := i >= 2; errors.Is(err, ErrCircuitOpen) != wantOpen {
+			t.Errorf("Info #%d = %v, want circuit open %v", i+1, err, wantOpen)
+		}
+		if i == 1 {
+			opened = dials.Load()
+		}
+	}
+	if n := dials.Load(); n != opened {
+		t.Errorf("%d dials once the circuit opened, want none", n-opened)
+	}
+}
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// maxStackSize caps the size of a captured stack trace
+const maxStackSize = 64 << 10
+
+// packagePath is this package's import path, used to skip its own frames
+var packagePath = reflect.TypeOf(Logger{}).PkgPath()
+
+// callerFrame returns the first stack frame outside this package and the
+// standard log packages, which is where the user logged from
+func callerFrame() (runtime.Frame, bool) {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		switch functionPackage(frame.Function) {
+		case packagePath, "log", "log/slog":
+		default:
+			return frame, true
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+// functionPackage returns the package path of a function name as reported
+// by runtime, e.g. "net/http" for "net/http.(*Client).Do"
+func functionPackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+// stacktrace returns the current goroutine's stack, truncated to
+// maxStackSize
+func stacktrace() string {
+	buf := make([]byte, 4<<10)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) || len(buf) >= maxStackSize {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
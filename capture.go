@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// CaptureSink records the GELF payloads of a Logger built by NewTestLogger,
+// so tests can assert on what would have been sent to Graylog
+type CaptureSink struct {
+	mu       sync.Mutex
+	payloads [][]byte
+}
+
+// NewTestLogger returns a Logger that sends nothing and writes nothing
+// locally, and the sink that records its messages. opts are applied as for
+// NewLoggerWithOptions.
+func NewTestLogger(opts ...Option) (*Logger, *CaptureSink) {
+	opts = append([]Option{WithDisabledRemote(), WithDisabledLocal()}, opts...)
+	l, err := NewLoggerWithOptions(opts...)
+	if err != nil {
+		panic("graylog: NewTestLogger: " + err.Error()) // unreachable without a remote
+	}
+	l.sink = &CaptureSink{}
+	return l, l.sink
+}
+
+// record stores a copy of payload
+func (s *CaptureSink) record(payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payloads = append(s.payloads, append([]byte(nil), payload...))
+}
+
+// Payloads returns the encoded GELF documents recorded so far, oldest first
+func (s *CaptureSink) Payloads() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.payloads...)
+}
+
+// Messages returns the GELF documents recorded so far, decoded, oldest
+// first. Numbers decode as float64, so a message's level is e.g. 6.0.
+func (s *CaptureSink) Messages() []map[string]interface{} {
+	payloads := s.Payloads()
+	messages := make([]map[string]interface{}, 0, len(payloads))
+	for _, payload := range payloads {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(payload, &doc); err == nil {
+			messages = append(messages, doc)
+		}
+	}
+	return messages
+}
+
+// Reset discards the recorded messages
+func (s *CaptureSink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payloads = nil
+}
@@ -0,0 +1,45 @@
+package logger
+
+import "testing"
+
+func TestCaptureSink(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		log   func(l *Logger)
+		want  []string
+		level float64 // of the first message
+	}{
+		{"info", nil, func(l *Logger) { l.Info("hello", LogData{}) }, []string{"hello"}, 6},
+		{"in order", nil, func(l *Logger) {
+			l.Error("first", LogData{})
+			l.Warn("second", LogData{})
+		}, []string{"first", "second"}, 3},
+		{"filtered", []Option{WithLevel(LevelWarn)}, func(l *Logger) { l.Info("hidden", LogData{}) }, nil, 0},
+		{"child", nil, func(l *Logger) { l.WithFields(LogData{AppName: "child"}).Info("from child", LogData{}) }, []string{"from child"}, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, sink := NewTestLogger(tt.opts...)
+			tt.log(l)
+
+			messages := sink.Messages()
+			if len(messages) != len(tt.want) || len(sink.Payloads()) != len(tt.want) {
+				t.Fatalf("%d messages recorded, want %d", len(messages), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got := messages[i]["short_message"]; got != want {
+					t.Errorf("message %d = %v, want %q", i, got, want)
+				}
+			}
+			if len(messages) > 0 && messages[0]["level"] != tt.level {
+				t.Errorf("level = %v, want %v", messages[0]["level"], tt.level)
+			}
+
+			sink.Reset()
+			if n := len(sink.Payloads()); n != 0 {
+				t.Errorf("%d messages after Reset, want 0", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Clone returns a separately configurable copy of l, e.g. to give a
+// sub-component its own AppName or level. Unlike WithFields, changing the
+// copy's fields, level, output or hooks doesn't affect l. The copy still
+// shares l's connection to Graylog and the machinery behind it, such as the
+// async buffer, spool and statistics, and closing either closes both.
+// Transport settings such as GraylogHost, GraylogPort, Protocol, DialFunc
+// and Async must not be changed on the copy: it would keep using l's
+// connection and worker, which were set up with l's values.
+func (l *Logger) Clone() *Logger {
+	c := *l
+	c.logger = cloneLogrus(l.logger)
+	c.level = newLevelVar(l.GetLevel())
+	c.fields = cloneLogData(l.fields)
+	c.extractors = slices.Clone(l.extractors)
+	c.FieldLimits = maps.Clone(l.FieldLimits)
+	c.FieldNameMap = maps.Clone(l.FieldNameMap)
+	c.Destinations = slices.Clone(l.Destinations)
+	c.RedactFields = slices.Clone(l.RedactFields)
+	c.RedactPatterns = slices.Clone(l.RedactPatterns)
+	return &c
+}
+
+// cloneLogData copies data, including its field maps
+func cloneLogData(data LogData) LogData {
+	data.Fields = maps.Clone(data.Fields)
+	data.extra = maps.Clone(data.extra)
+	return data
+}
+
+// cloneLogrus copies a logrus logger's settings and hooks
+func cloneLogrus(l *logrus.Logger) *logrus.Logger {
+	hooks := make(logrus.LevelHooks, len(l.Hooks))
+	for level, levelHooks := range l.Hooks {
+		hooks[level] = slices.Clone(levelHooks)
+	}
+	return &logrus.Logger{
+		Out:          l.Out,
+		Hooks:        hooks,
+		Formatter:    l.Formatter,
+		ReportCaller: l.ReportCaller,
+		Level:        l.GetLevel(),
+		ExitFunc:     l.ExitFunc,
+		BufferPool:   l.BufferPool,
+	}
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCloneSharesTransport(t *testing.T) {
+	graylog := &testGraylog{}
+	var dials atomic.Int32
+	l, err := NewLoggerWithOptions(
+		WithGraylog("graylog", "12201", "tcp"),
+		WithDialFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			dials.Add(1)
+			return graylog.dial(ctx, network, address)
+		}),
+		WithAsync(8, OverflowBlock),
+		WithLevel(LevelInfo),
+		WithDisabledIPLookup(),
+		WithDisabledLocal(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := l.Clone()
+	c.SetLevel(LevelDebug)
+	if got := l.GetLevel(); got != LevelInfo {
+		t.Errorf("original level = %v after SetLevel on the clone, want %v", got, LevelInfo)
+	}
+	if err := l.Debug("filtered", LogData{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, logger := range []*Logger{l, c} {
+		if err := logger.Info("info", LogData{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Debug("debug", LogData{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := graylog.wait(t, 3), []string{"info", "info", "debug"}; !slices.Equal(got, want) {
+		t.Errorf("received %q, want %q", got, want)
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("%d dials, want 1 connection shared by the clone", n)
+	}
+	if sent := l.Stats().Sent; sent != 3 {
+		t.Errorf("original Sent = %d, want 3 including the clone's", sent)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Info("after close", LogData{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("original Info after closing the clone = %v, want %v", err, ErrClosed)
+	}
+}
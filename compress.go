@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+)
+
+// Supported values for Logger.Compression
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZlib = "zlib"
+)
+
+// validateCompression checks that Compression is known
+func (l *Logger) validateCompression() error {
+	switch l.Compression {
+	case "", CompressionNone, CompressionGzip, CompressionZlib:
+		return nil
+	}
+	return fmt.Errorf("graylog: invalid compression %q", l.Compression)
+}
+
+// compression returns the algorithm for a payload of size bytes:
+// Compression, or none below CompressionThreshold and for syslog
+func (l *Logger) compression(size int) string {
+	if size < l.CompressionThreshold || l.syslog() {
+		return CompressionNone
+	}
+	return l.Compression
+}
+
+// compress encodes data with the given algorithm. Both gzip and zlib keep
+// their magic bytes so Graylog can detect the encoding on its own.
+func compress(algorithm string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch algorithm {
+	case "", CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case CompressionZlib:
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", algorithm)
+	}
+
+	return buf.Bytes(), nil
+}
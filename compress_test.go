@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+)
+
+func TestCompressionOption(t *testing.T) {
+	tests := []struct {
+		compression string
+		want        string
+		wantErr     bool
+	}{
+		{"", "", false},
+		{"none", CompressionNone, false},
+		{"gzip", CompressionGzip, false},
+		{"GZIP", CompressionGzip, false},
+		{" Zlib ", CompressionZlib, false},
+		{"brotli", "", true},
+		{"gz", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.compression, func(t *testing.T) {
+			l, err := NewLoggerWithOptions(
+				WithGraylog("127.0.0.1", "12201", "udp"),
+				WithCompression(tt.compression),
+				WithDisabledIPLookup(),
+				WithDisabledLocal(),
+			)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("no error for compression %q", tt.compression)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			if l.Compression != tt.want {
+				t.Errorf("Compression = %q, want %q", l.Compression, tt.want)
+			}
+			if err := l.Info("hello", LogData{}); err != nil {
+				t.Errorf("Info = %v", err)
+			}
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"short_message":"hello"}`), 50)
+	tests := []struct {
+		algorithm  string
+		decompress func(io.Reader) (io.Reader, error)
+	}{
+		{CompressionNone, func(r io.Reader) (io.Reader, error) { return r, nil }},
+		{CompressionGzip, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{CompressionZlib, func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			compressed, err := compress(tt.algorithm, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := tt.decompress(bytes.NewReader(compressed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Error("round trip differs")
+			}
+		})
+	}
+	if _, err := compress("brotli", data); err == nil {
+		t.Error("no error for an unknown algorithm")
+	}
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LocalFormat is the format of the local output
+type LocalFormat int
+
+const (
+	// LocalJSON writes logrus JSON: the message, level and time with the
+	// additional fields as top-level keys
+	LocalJSON LocalFormat = iota
+	// LocalText writes logrus text, colored when writing to a terminal;
+	// see WithConsoleFormat
+	LocalText
+	// LocalGELF writes the GELF payload sent to Graylog, one per line
+	LocalGELF
+)
+
+// rawFormatter writes the message as is, for LocalGELF
+type rawFormatter struct{}
+
+func (rawFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	return append([]byte(entry.Message), '\n'), nil
+}
+
+// setLocalFormatter sets the logrus formatter for LocalFormat and the
+// current output
+func (l *Logger) setLocalFormatter() {
+	switch l.LocalFormat {
+	case LocalText:
+		// logrus only detects a terminal on an *os.File, not on the
+		// fallbackWriter around it, so decide on colors here
+		out := l.logger.Out
+		if fw, ok := out.(*fallbackWriter); ok {
+			out = fw.out
+		}
+		color := isTerminal(out)
+		l.logger.SetFormatter(&logrus.TextFormatter{ForceColors: color, DisableColors: !color})
+	case LocalGELF:
+		l.logger.SetFormatter(rawFormatter{})
+	default:
+		l.logger.SetFormatter(&logrus.JSONFormatter{})
+	}
+}
+
+// writeLocal writes a message to the local output. With LocalGELF that is
+// the GELF payload; otherwise it is the message text with the additional
+// fields as logrus fields, so the local output gets its own structure
+// instead of GELF JSON inside a JSON message.
+func (l *Logger) writeLocal(level Level, doc map[string]interface{}, jsonData []byte) {
+	if l.LocalFormat == LocalGELF {
+		l.logger.Log(level.logrusLevel(), string(jsonData))
+		return
+	}
+
+	fields := logrus.Fields{}
+	for key, value := range doc {
+		if strings.HasPrefix(key, "_") && key != "_level_name" {
+			fields[key[1:]] = value
+		}
+	}
+	message, ok := doc["full_message"]
+	if !ok {
+		message = doc["short_message"]
+	}
+	l.logger.WithFields(fields).Log(level.logrusLevel(), message)
+}
+
+// fallbackWriter writes to out and, if that fails, to fallback, so the
+// local output can't lose messages silently
+type fallbackWriter struct {
+	out, fallback io.Writer
+}
+
+func (w *fallbackWriter) Write(p []byte) (int, error) {
+	n, err := w.out.Write(p)
+	if err == nil {
+		return n, nil
+	}
+	if _, fallbackErr := w.fallback.Write(p); fallbackErr != nil {
+		return n, err
+	}
+	return len(p), nil
+}
+
+// isTerminal reports whether w is a file attached to a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && isTerminalFd(f.Fd())
+}
+
+// emergencyOutput returns EmergencyOutput, or os.Stderr if it isn't set
+func (l *Logger) emergencyOutput() io.Writer {
+	if l.EmergencyOutput != nil {
+		return l.EmergencyOutput
+	}
+	return os.Stderr
+}
+
+// levelOutputHook writes entries at minLevel or above to out, for
+// WithLevelOutput
+type levelOutputHook struct {
+	minLevel Level
+	mu       sync.Mutex
+	out      io.Writer
+}
+
+func (h *levelOutputHook) Levels() []logrus.Level {
+	var levels []logrus.Level
+	for level := h.minLevel; level <= LevelFatal; level++ {
+		levels = append(levels, level.logrusLevel())
+	}
+	return levels
+}
+
+func (h *levelOutputHook) Fire(entry *logrus.Entry) error {
+	line, err := entry.Logger.Formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err = h.out.Write(line)
+	return err
+}
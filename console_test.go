@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestIsTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	file, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+
+	tests := []struct {
+		name string
+		w    io.Writer
+		want bool
+	}{
+		{"buffer", &bytes.Buffer{}, false},
+		{"pipe", w, false},
+		{"file", file, false},
+		{"null device", null, false},
+	}
+	if tty, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0); err == nil {
+		defer tty.Close()
+		tests = append(tests, struct {
+			name string
+			w    io.Writer
+			want bool
+		}{"pseudo-terminal", tty, true})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTerminal(tt.w); got != tt.want {
+				t.Errorf("isTerminal = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsoleColors(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := NewLoggerWithOptions(WithDisabledRemote(), WithConsoleFormat(), WithOutput(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := l.logger.Formatter.(*logrus.TextFormatter)
+	if f.ForceColors || !f.DisableColors {
+		t.Errorf("colors forced for a buffer")
+	}
+	l.Info("hello", LogData{})
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("escape sequences in %q", buf.String())
+	}
+
+	tty, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skip("no pseudo-terminal:", err)
+	}
+	defer tty.Close()
+	// The output is wrapped for EmergencyOutput, hiding the terminal from
+	// logrus, so the Logger must detect it
+	l.SetOutput(tty)
+	if f := l.logger.Formatter.(*logrus.TextFormatter); !f.ForceColors {
+		t.Error("colors not forced for a terminal")
+	}
+}
+
+func TestWriteLocalUnencodableField(t *testing.T) {
+	tests := []struct {
+		name   string
+		format LocalFormat
+		want   string
+	}{
+		{"json", LocalJSON, `"msg":"hello"`},
+		{"text", LocalText, "msg=hello"},
+		{"gelf", LocalGELF, `"_marshal_error"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, emergency bytes.Buffer
+			l, sink := NewTestLogger(WithLocalFormat(tt.format), WithOutput(&out), WithEmergencyOutput(&emergency))
+			l.DisableLocal = false // NewTestLogger turns off the output under test
+			err := l.Info("hello", LogData{Fields: map[string]interface{}{"ch": make(chan int)}})
+			if err == nil {
+				t.Error("no marshal error")
+			}
+
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("local output %q doesn't contain %q", out.String(), tt.want)
+			}
+			if emergency.Len() > 0 {
+				t.Errorf("emergency output %q", emergency.String())
+			}
+			if messages := sink.Messages(); len(messages) != 1 || messages[0]["_marshal_error"] == nil {
+				t.Errorf("recorded %v, want the fallback message", messages)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"context"
+	"maps"
+)
+
+// ContextExtractor pulls a field from a context, e.g. a transaction ID put
+// there by middleware. key is a LogData JSON name such as "tr_id" or
+// "channel", or else a custom field name.
+type ContextExtractor func(ctx context.Context) (key string, value string, ok bool)
+
+// addContextFields fills data from the extractors registered with
+// WithContextFields. Fields already set on data win.
+func (l *Logger) addContextFields(ctx context.Context, data *LogData) {
+	if len(l.extractors) == 0 {
+		return
+	}
+
+	fields := data.stringFields()
+	copied := false
+	for _, extract := range l.extractors {
+		key, value, ok := extract(ctx)
+		if !ok {
+			continue
+		}
+		if field, ok := fields[key]; ok {
+			if *field == "" {
+				*field = value
+			}
+			continue
+		}
+		if _, ok := data.Fields[key]; ok {
+			continue
+		}
+		if !copied {
+			data.Fields = maps.Clone(data.Fields) // don't modify the caller's map
+			if data.Fields == nil {
+				data.Fields = map[string]interface{}{}
+			}
+			copied = true
+		}
+		data.Fields[key] = value
+	}
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// deduper collapses bursts of identical consecutive messages. The first
+// message of a burst is logged; repeats within the window are suppressed and
+// reported by a single summary when the burst ends or the window closes.
+type deduper struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	key     string    // identifies the current burst's message
+	since   time.Time // when the current burst started
+	repeats int       // suppressed repeats in the current burst
+	timer   *time.Timer
+
+	// last suppressed message, repeated in the summary
+	logger  *Logger
+	level   Level
+	message string
+	data    LogData
+}
+
+// summary is a pending "repeated N times" message
+type summary struct {
+	logger  *Logger
+	level   Level
+	message string
+	data    LogData
+	repeats int
+}
+
+// dedupKey identifies a message by level, text and fields. The transaction
+// ID is left out, as it differs between otherwise identical messages.
+func dedupKey(level Level, message string, data LogData) string {
+	data.TransactionID = ""
+	fixed, _ := json.Marshal(data)
+	return fmt.Sprintf("%d\x00%s\x00%s\x00%v", level, message, fixed, data.Fields)
+}
+
+// suppress reports whether the message repeats the current burst. A message
+// that ends a burst first logs the burst's summary.
+func (d *deduper) suppress(l *Logger, level Level, message string, data LogData) bool {
+	key := dedupKey(level, message, data)
+	now := time.Now()
+
+	d.mu.Lock()
+	if key == d.key && now.Sub(d.since) < d.window {
+		d.repeats++
+		d.logger, d.level, d.message, d.data = l, level, message, data
+		if d.timer == nil {
+			d.timer = time.AfterFunc(d.since.Add(d.window).Sub(now), d.expire)
+		}
+		d.mu.Unlock()
+		return true
+	}
+
+	pending := d.takeLocked()
+	d.key, d.since = key, now
+	d.mu.Unlock()
+
+	pending.log()
+	return false
+}
+
+// expire ends the burst when its window closes
+func (d *deduper) expire() {
+	d.mu.Lock()
+	pending := d.takeLocked()
+	d.key = ""
+	d.mu.Unlock()
+
+	pending.log()
+}
+
+// flush logs the summary of the current burst, if any
+func (d *deduper) flush() {
+	d.expire()
+}
+
+// takeLocked returns the current burst's summary and resets the burst
+func (d *deduper) takeLocked() *summary {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.repeats == 0 {
+		return nil
+	}
+
+	s := &summary{logger: d.logger, level: d.level, message: d.message, data: d.data, repeats: d.repeats}
+	d.repeats = 0
+	d.logger, d.data = nil, LogData{}
+	return s
+}
+
+// log writes the summary, bypassing deduplication
+func (s *summary) log() {
+	if s == nil {
+		return
+	}
+	s.data.setExtra("_repeated", s.repeats)
+	message := fmt.Sprintf("%s (repeated %d times)", s.message, s.repeats)
+	s.logger.log(context.Background(), "", s.level, message, s.data)
+}
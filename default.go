@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	defaultLogger     atomic.Pointer[Logger]
+	defaultLoggerOnce sync.Once
+)
+
+// Default returns the package-level default Logger, which the top-level
+// functions such as Info log with. Until SetDefault is called it is a
+// Logger that writes locally to stdout and sends nothing to Graylog, so it
+// is safe to use before configuration.
+func Default() *Logger {
+	defaultLoggerOnce.Do(func() {
+		if defaultLogger.Load() == nil {
+			l, _ := NewLoggerWithOptions(WithDisabledRemote())
+			defaultLogger.CompareAndSwap(nil, l)
+		}
+	})
+	return defaultLogger.Load()
+}
+
+// SetDefault makes l the Logger returned by Default. The previous default
+// is not closed.
+func SetDefault(l *Logger) {
+	defaultLogger.Store(l)
+}
+
+// loggerKey is the context key for ContextWithLogger
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying l, e.g. a request-scoped
+// Logger made with WithFields in middleware, for LoggerFromContext
+func ContextWithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// LoggerFromContext returns the Logger stored in ctx by ContextWithLogger.
+// If ctx carries none, it returns Default, so the result is never nil.
+func LoggerFromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok && l != nil {
+		return l
+	}
+	return Default()
+}
+
+// Log logs a message with the default Logger; see Logger.Log
+func Log(level Level, message string, data LogData) error {
+	return Default().Log(level, message, data)
+}
+
+// Trace logs a message at LevelTrace with the default Logger
+func Trace(message string, data LogData) error {
+	return Default().Trace(message, data)
+}
+
+// Debug logs a message at LevelDebug with the default Logger
+func Debug(message string, data LogData) error {
+	return Default().Debug(message, data)
+}
+
+// Info logs a message at LevelInfo with the default Logger
+func Info(message string, data LogData) error {
+	return Default().Info(message, data)
+}
+
+// Warn logs a message at LevelWarn with the default Logger
+func Warn(message string, data LogData) error {
+	return Default().Warn(message, data)
+}
+
+// Error logs a message at LevelError with the default Logger
+func Error(message string, data LogData) error {
+	return Default().Error(message, data)
+}
+
+// Fatal logs a message at LevelFatal with the default Logger, closes it and
+// exits the process; see Logger.Fatal
+func Fatal(message string, data LogData) {
+	Default().Fatal(message, data)
+}
@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Destination is an extra Graylog endpoint that receives a copy of every
+// message. With destinations set, Stats count each endpoint's deliveries
+// and failures separately.
+type Destination struct {
+	Host     string
+	Port     string
+	Protocol string // as Logger.Protocol
+
+	// MinLevel, if above LevelTrace, routes only messages at that level or
+	// above to the destination, e.g. errors to a dedicated stream. They are
+	// sent right away from Log, bypassing the async buffer and batching, so
+	// a backlog of other messages can't delay them.
+	MinLevel Level
+}
+
+// setupEndpoints validates the Destinations and gives each one its own
+// connection
+func (l *Logger) setupEndpoints() error {
+	for _, d := range l.Destinations {
+		d.Protocol = normalizeProtocol(d.Protocol)
+		if !validProtocol(d.Protocol) {
+			return fmt.Errorf("graylog: destination %s:%s: invalid protocol %q", d.Host, d.Port, d.Protocol)
+		}
+
+		e := *l
+		e.GraylogHost = d.Host
+		e.GraylogPort = d.Port
+		e.Protocol = d.Protocol
+		e.minLevel = d.MinLevel
+		e.Destinations = nil
+		e.endpoints = nil
+		e.breaker = &breaker{}
+		e.conn = &connection{}
+		e.async = nil
+		e.initReconnect()
+		if err := e.validateAddress(); err != nil {
+			return err
+		}
+		if err := e.validateFormat(); err != nil {
+			return err
+		}
+		e.connect()
+		l.endpoints = append(l.endpoints, &e)
+	}
+	return nil
+}
+
+// streamOnly reports whether every endpoint uses a stream protocol, "tcp"
+// or "unix", so batches can be newline delimited
+func (l *Logger) streamOnly() bool {
+	if l.Protocol != "tcp" && l.Protocol != "unix" {
+		return false
+	}
+	for _, e := range l.endpoints {
+		if e.minLevel == LevelTrace && e.Protocol != "tcp" && e.Protocol != "unix" {
+			return false
+		}
+	}
+	return true
+}
+
+// route sends a message to the destinations with a MinLevel it meets
+func (l *Logger) route(ctx context.Context, level Level, logData []byte) error {
+	var errs []error
+	for _, e := range l.endpoints {
+		if e.minLevel > LevelTrace && level >= e.minLevel {
+			errs = append(errs, e.sendToEndpoint(ctx, logData, 1))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendToGraylog delivers log data holding the given number of messages,
+// spooling it if that fails and a spool is configured
+func (l *Logger) sendToGraylog(ctx context.Context, logData []byte, messages int) error {
+	err := l.deliver(ctx, logData, messages)
+	if err != nil && l.spool != nil {
+		l.spoolPayload(logData, messages)
+	}
+	return err
+}
+
+// deliver sends log data to Graylog and every Destination without a
+// MinLevel. Endpoints are written concurrently so one that is down or slow
+// doesn't hold up the others; the returned error joins the failures, each
+// naming its endpoint.
+func (l *Logger) deliver(ctx context.Context, logData []byte, messages int) error {
+	if len(l.endpoints) == 0 {
+		return l.sendToEndpoint(ctx, logData, messages)
+	}
+
+	errs := make([]error, len(l.endpoints)+1)
+	var wg sync.WaitGroup
+	for i, e := range append([]*Logger{l}, l.endpoints...) {
+		if e.minLevel > LevelTrace {
+			continue // sent by route
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = e.sendToEndpoint(ctx, logData, messages)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
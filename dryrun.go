@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// dryRunConn is a net.Conn that records writes instead of sending them
+type dryRunConn struct {
+	net.Conn // nil; only the methods below are used by send
+	frames   [][]byte
+}
+
+func (c *dryRunConn) Write(p []byte) (int, error) {
+	c.frames = append(c.frames, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (c *dryRunConn) SetWriteDeadline(time.Time) error { return nil }
+
+// dryRun frames payload, already compressed, exactly as a real send would
+// and hands the frames to OnDryRun: one per datagram or GELF chunk for
+// datagram protocols, the newline-terminated message for streams and the
+// request body for HTTP
+func (l *Logger) dryRun(ctx context.Context, payload []byte) error {
+	frames := [][]byte{payload}
+	if !isHTTP(l.Protocol) {
+		conn := &dryRunConn{}
+		if err := l.send(ctx, conn, payload); err != nil {
+			return err
+		}
+		frames = conn.frames
+	}
+	if l.OnDryRun != nil {
+		l.OnDryRun(l.Protocol, l.address(), frames)
+	}
+	return nil
+}
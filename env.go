@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+)
+
+// Defaults for NewLoggerFromEnv
+const (
+	defaultEnvPort     = "12201"
+	defaultEnvProtocol = "udp"
+)
+
+// NewLoggerFromEnv initializes a new logger from the environment:
+//
+//	GRAYLOG_HOST      Graylog host, required unless remote output is disabled
+//	GRAYLOG_PORT      Graylog port, "12201" if unset
+//	GRAYLOG_PROTOCOL  "udp" (default), "tcp", "http" or "https"
+//	LOG_LEVEL         minimum level, e.g. "info"; see ParseLevel
+//	APP_NAME          default app name
+//
+// opts are applied after the environment, so they override it.
+func NewLoggerFromEnv(opts ...Option) (*Logger, error) {
+	host := os.Getenv("GRAYLOG_HOST")
+	port := envOr("GRAYLOG_PORT", defaultEnvPort)
+	protocol := envOr("GRAYLOG_PROTOCOL", defaultEnvProtocol)
+
+	envOpts := []Option{WithGraylog(host, port, protocol)}
+	if name := os.Getenv("APP_NAME"); name != "" {
+		envOpts = append(envOpts, WithAppName(name))
+	}
+	if name := os.Getenv("LOG_LEVEL"); name != "" {
+		level, err := ParseLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("graylog: invalid LOG_LEVEL %q", name)
+		}
+		envOpts = append(envOpts, WithLevel(level))
+	}
+
+	l, err := NewLoggerWithOptions(append(envOpts, opts...)...)
+	if err != nil && host == "" {
+		return nil, fmt.Errorf("graylog: GRAYLOG_HOST is not set: %w", err)
+	}
+	return l, err
+}
+
+// envOr returns the environment variable key, or fallback if it is unset
+// or empty
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
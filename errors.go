@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldsError is implemented by errors that carry log fields. LogError
+// merges the fields of every FieldsError in the wrap chain.
+type FieldsError interface {
+	error
+	LogFields() LogData
+}
+
+// CodedError is implemented by domain errors that carry a code and a
+// category. LogError records the innermost one in the wrap chain as
+// _error_code and _error_category, so Graylog can group errors by code.
+type CodedError interface {
+	Code() string
+	Category() string
+}
+
+// fieldsError attaches log fields to an error
+type fieldsError struct {
+	err  error
+	data LogData
+}
+
+// ErrorWithFields wraps err so that LogError adds data's fields to the log.
+// It returns nil if err is nil.
+func ErrorWithFields(err error, data LogData) error {
+	if err == nil {
+		return nil
+	}
+	return &fieldsError{err: err, data: data}
+}
+
+func (e *fieldsError) Error() string      { return e.err.Error() }
+func (e *fieldsError) Unwrap() error      { return e.err }
+func (e *fieldsError) LogFields() LogData { return e.data }
+
+// LogError logs err at LevelError with err.Error() as the message. The wrap
+// chain is recorded as _error_type (the type of err), _error_chain (the
+// types of the wrapped errors, outermost first) and _error_cause (the
+// innermost message), which are sanitized and redacted like any field.
+// Fields carried by FieldsError values in the chain are added, with outer
+// errors taking precedence and data over all of them, as are the code and
+// category of the innermost CodedError. A nil err logs nothing.
+func (l *Logger) LogError(err error, data LogData) error {
+	if err == nil || !l.Enabled(LevelError) {
+		return nil
+	}
+
+	var chain []string
+	var fields []LogData
+	var coded CodedError
+	cause := err
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if _, ok := e.(*fieldsError); !ok {
+			chain = append(chain, fmt.Sprintf("%T", e))
+		}
+		if fe, ok := e.(FieldsError); ok {
+			fields = append(fields, fe.LogFields())
+		}
+		if ce, ok := e.(CodedError); ok {
+			coded = ce
+		}
+		cause = e
+	}
+
+	merged := LogData{}
+	for i := len(fields) - 1; i >= 0; i-- {
+		merged = mergeLogData(merged, fields[i])
+	}
+	data = mergeLogData(merged, data)
+
+	data.setExtra("_error_type", chain[0])
+	data.setExtra("_error_chain", strings.Join(chain, ", ")) // GELF fields can't be arrays
+	data.setExtra("_error_cause", cause.Error())
+	if coded != nil {
+		data.setExtra("_error_code", coded.Code())
+		data.setExtra("_error_category", coded.Category())
+	}
+
+	return l.Log(LevelError, err.Error(), data)
+}
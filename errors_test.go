@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestLogErrorRedactsCause(t *testing.T) {
+	cause := errors.New("card 4111111111111111 declined")
+	tests := []struct {
+		name      string
+		opts      []Option
+		err       error
+		wantShort string
+		wantCause string
+	}{
+		{
+			"pattern",
+			[]Option{WithRedactPatterns(PANPattern)},
+			fmt.Errorf("charge: %w", cause),
+			"charge: card **** declined",
+			"card **** declined",
+		},
+		{
+			"pattern, unwrapped",
+			[]Option{WithRedactPatterns(PANPattern)},
+			cause,
+			"card **** declined",
+			"card **** declined",
+		},
+		{
+			"field name",
+			[]Option{WithRedactFields("error_cause")},
+			fmt.Errorf("charge: %w", cause),
+			"charge: card 4111111111111111 declined",
+			"****",
+		},
+		{
+			"control characters",
+			[]Option{WithRedactPatterns(regexp.MustCompile(`secret`))},
+			fmt.Errorf("wrap: %w", errors.New("bad\x00 secret")),
+			"wrap: bad ****",
+			"bad ****",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, sink := NewTestLogger(tt.opts...)
+			if err := l.LogError(tt.err, LogData{}); err != nil {
+				t.Fatal(err)
+			}
+			messages := sink.Messages()
+			if len(messages) != 1 {
+				t.Fatalf("%d messages, want 1", len(messages))
+			}
+			if got := messages[0]["short_message"]; got != tt.wantShort {
+				t.Errorf("short_message = %q, want %q", got, tt.wantShort)
+			}
+			if got := messages[0]["_error_cause"]; got != tt.wantCause {
+				t.Errorf("_error_cause = %q, want %q", got, tt.wantCause)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// limitFields cuts string additional fields over their limit, marks doc
+// with _field_truncated and returns the names of the fields it cut
+func (l *Logger) limitFields(doc map[string]interface{}) []string {
+	if l.MaxFieldBytes <= 0 && len(l.FieldLimits) == 0 {
+		return nil
+	}
+
+	var truncated []string
+	for key, value := range doc {
+		s, ok := value.(string)
+		if !ok || !strings.HasPrefix(key, "_") || key == "_level_name" {
+			continue
+		}
+		name := key[1:]
+		limit, ok := l.FieldLimits[name]
+		if !ok {
+			limit = l.MaxFieldBytes
+		}
+		if limit <= 0 || len(s) <= limit {
+			continue
+		}
+		for limit > 0 && !utf8.RuneStart(s[limit]) {
+			limit--
+		}
+		doc[key] = s[:limit]
+		truncated = append(truncated, name)
+	}
+	if len(truncated) == 0 {
+		return nil
+	}
+	slices.Sort(truncated)
+	doc["_field_truncated"] = strings.Join(truncated, ",") // GELF fields can't be arrays
+	return truncated
+}
@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"maps"
+	"strings"
+)
+
+// reservedFields are names a custom field may not take as-is: the GELF
+// reserved keys plus the keys LogData already emits
+var reservedFields = map[string]bool{
+	"id": true, "version": true, "host": true, "short_message": true,
+	"full_message": true, "timestamp": true, "level": true, "facility": true,
+	"line": true, "file": true, "message": true, "ip_address": true,
+	"appname": true, "hostname": true, "tr_id": true, "channel": true,
+	"bank_code": true, "reference_id": true, "rrn": true, "publish_id": true,
+	"cf_trid": true, "device_info": true, "param_a": true, "param_b": true,
+	"param_c": true, "level_name": true, "func": true,
+	"stacktrace": true, "error_type": true, "error_chain": true, "error_cause": true,
+	"marshal_error": true, "repeated": true, "truncated": true,
+	"duration_ms": true, "trace_id": true, "span_id": true, "pid": true,
+	"go_version": true, "num_goroutine": true, "app_version": true,
+	"field_truncated": true, "error_code": true, "error_category": true,
+}
+
+// fieldKey converts a custom field name to its GELF additional field key.
+// Characters GELF doesn't allow are replaced with '_', and names that clash
+// with a reserved key are prefixed with "custom_". An empty result means the
+// field is dropped.
+func fieldKey(name string) string {
+	name = fieldName(name)
+	if name == "" {
+		return ""
+	}
+	if reservedFields[name] {
+		name = "custom_" + name
+	}
+	return "_" + name
+}
+
+// fieldName strips leading underscores from name and replaces characters
+// GELF doesn't allow with '_'
+func fieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '.' || r == '-' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.TrimLeft(name, "_"))
+}
+
+// renameFields applies FieldNameMap to doc's additional fields. Every
+// field is renamed from its original name, so a mapping's target can be
+// another mapping's source.
+func (l *Logger) renameFields(doc map[string]interface{}) {
+	renamed := map[string]interface{}{}
+	for from, to := range l.FieldNameMap {
+		key := "_" + fieldName(from)
+		value, ok := doc[key]
+		if !ok || fieldName(to) == "" {
+			continue
+		}
+		delete(doc, key)
+		renamed["_"+fieldName(to)] = value
+	}
+	maps.Copy(doc, renamed)
+}
+
+// stringFields returns pointers to d's string fields keyed by JSON name
+func (d *LogData) stringFields() map[string]*string {
+	return map[string]*string{
+		"timestamp":    &d.Timestamp,
+		"level":        &d.Level,
+		"message":      &d.Message,
+		"full_message": &d.FullMessage,
+		"ip_address":   &d.IPAddress,
+		"appname":      &d.AppName,
+		"hostname":     &d.Hostname,
+		"tr_id":        &d.TransactionID,
+		"channel":      &d.Channel,
+		"bank_code":    &d.BankCode,
+		"reference_id": &d.ReferenceID,
+		"rrn":          &d.RRN,
+		"publish_id":   &d.PublishID,
+		"cf_trid":      &d.CFTrID,
+		"device_info":  &d.DeviceInfo,
+		"param_a":      &d.ParamA,
+		"param_b":      &d.ParamB,
+		"param_c":      &d.ParamC,
+	}
+}
+
+// mergeLogData returns base with every non-empty field of override applied
+// on top. Custom fields are merged key by key.
+func mergeLogData(base, override LogData) LogData {
+	merged := base
+	dst := merged.stringFields()
+	for key, value := range override.stringFields() {
+		if *value != "" {
+			*dst[key] = *value
+		}
+	}
+
+	merged.Fields = mergeFields(base.Fields, override.Fields)
+	merged.extra = mergeFields(base.extra, override.extra)
+	return merged
+}
+
+// mergeFields returns the union of two field maps, override winning
+func mergeFields(base, override map[string]interface{}) map[string]interface{} {
+	if len(override) == 0 {
+		return base
+	}
+	if len(base) == 0 {
+		return override
+	}
+	merged := maps.Clone(base)
+	maps.Copy(merged, override)
+	return merged
+}
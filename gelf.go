@@ -0,0 +1,368 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// gelfVersion is the GELF spec version of the documents we emit
+const gelfVersion = "1.1"
+
+// DefaultMaxMessageBytes is the largest GELF message that fits in a chunked
+// UDP datagram sequence, and the default for MaxMessageBytes
+const DefaultMaxMessageBytes = maxChunks * (maxChunkSize - chunkHeaderSize)
+
+// maxPooledBuffer is the largest buffer put back in bufferPool, so one huge
+// message doesn't pin its memory
+const maxPooledBuffer = 64 << 10
+
+// encodeBuffer is a buffer with an encoder writing into it
+type encodeBuffer struct {
+	buf  bytes.Buffer
+	enc  *json.Encoder
+	keys []string // scratch space for sorting additional field keys
+}
+
+// bufferPool holds the buffers GELF documents are encoded into
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		b := &encodeBuffer{}
+		b.enc = json.NewEncoder(&b.buf)
+		return b
+	},
+}
+
+// gelfKeyOrder is the order the GELF spec fields are encoded in; the
+// additional fields follow, sorted
+var gelfKeyOrder = []string{"version", "host", "short_message", "full_message", "timestamp", "level"}
+
+// isGELFKey reports whether key is in gelfKeyOrder
+func isGELFKey(key string) bool {
+	switch key {
+	case "version", "host", "short_message", "full_message", "timestamp", "level":
+		return true
+	}
+	return false
+}
+
+// marshalGELF encodes doc like json.Marshal, but with a fixed key order, so
+// the same message always encodes the same way, and into a pooled buffer so
+// for strings and numbers the only allocation is the returned copy. A
+// newline is kept in the copy's capacity, past its length, so sendTCP can
+// frame the message without another allocation.
+func marshalGELF(doc map[string]interface{}) ([]byte, error) {
+	b := bufferPool.Get().(*encodeBuffer)
+	b.buf.Reset()
+	defer func() {
+		if b.buf.Cap() <= maxPooledBuffer {
+			clear(b.keys)
+			b.keys = b.keys[:0]
+			bufferPool.Put(b)
+		}
+	}()
+
+	b.keys = b.keys[:0]
+	for key := range doc {
+		if !isGELFKey(key) {
+			b.keys = append(b.keys, key)
+		}
+	}
+	slices.Sort(b.keys)
+
+	b.buf.WriteByte('{')
+	first := true
+	field := func(key string, value interface{}) error {
+		if !first {
+			b.buf.WriteByte(',')
+		}
+		first = false
+		if err := b.encodeString(key); err != nil {
+			return err
+		}
+		b.buf.WriteByte(':')
+		return b.encode(value)
+	}
+	for _, key := range gelfKeyOrder {
+		if value, ok := doc[key]; ok {
+			if err := field(key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, key := range b.keys {
+		if err := field(key, doc[key]); err != nil {
+			return nil, fmt.Errorf("field %s: %w", key, err)
+		}
+	}
+	b.buf.WriteString("}\n")
+
+	encoded := bytes.Clone(b.buf.Bytes())
+	return encoded[:len(encoded)-1], nil
+}
+
+// encode appends the JSON encoding of v, without the encoder's newline.
+// Strings, integers and finite floats, e.g. the timestamp, are written
+// directly, as going through the encoder costs allocations for each.
+func (b *encodeBuffer) encode(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		return b.encodeString(v)
+	case int:
+		b.buf.Write(strconv.AppendInt(b.buf.AvailableBuffer(), int64(v), 10))
+		return nil
+	case float64:
+		if !math.IsInf(v, 0) && !math.IsNaN(v) {
+			b.buf.Write(appendJSONFloat(b.buf.AvailableBuffer(), v))
+			return nil
+		}
+	}
+	return b.encodeJSON(v)
+}
+
+// encodeString appends the JSON encoding of s. Taking a string rather than
+// an interface{} means keys are not boxed.
+func (b *encodeBuffer) encodeString(s string) error {
+	if !isPlainJSON(s) {
+		return b.encodeJSON(s)
+	}
+	b.buf.WriteByte('"')
+	b.buf.WriteString(s)
+	b.buf.WriteByte('"')
+	return nil
+}
+
+// encodeJSON appends v encoded by the encoder, without its newline
+func (b *encodeBuffer) encodeJSON(v interface{}) error {
+	if err := b.enc.Encode(v); err != nil {
+		return err
+	}
+	b.buf.Truncate(b.buf.Len() - 1)
+	return nil
+}
+
+// isPlainJSON reports whether s encodes as itself between quotes, i.e. is
+// printable ASCII without the characters json.Marshal escapes
+func isPlainJSON(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c < 0x20, c >= 0x7f, c == '"', c == '\\', c == '<', c == '>', c == '&':
+			return false
+		}
+	}
+	return true
+}
+
+// appendJSONFloat appends f formatted as json.Marshal does: without an
+// exponent unless it is tiny or huge, and then with as few digits as
+// possible
+func appendJSONFloat(dst []byte, f float64) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9
+		if n := len(dst); n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst
+}
+
+// TimestampEpoch is the TimestampFormat for GELF's Unix epoch seconds
+const TimestampEpoch = "epoch"
+
+// timestamp formats now for the GELF timestamp field: epoch seconds with
+// microsecond precision as the spec requires, or a string in
+// TimestampFormat's layout
+func (l *Logger) timestamp(now time.Time) interface{} {
+	if l.TimestampFormat == "" || l.TimestampFormat == TimestampEpoch {
+		return float64(now.UnixMicro()) / 1e6
+	}
+	return now.UTC().Format(l.TimestampFormat)
+}
+
+// buildGELF assembles the GELF document for a log entry. The level is sent
+// as its syslog severity, which Graylog alerting filters on, and by name in
+// _level_name. The LogData fields other than the message and hostname become
+// additional fields.
+func (l *Logger) buildGELF(now time.Time, level Level, data LogData) map[string]interface{} {
+	shortMessage, _, multiline := strings.Cut(data.Message, "\n")
+
+	doc := map[string]interface{}{
+		"version":       gelfVersion,
+		"short_message": shortMessage,
+		"timestamp":     l.timestamp(now),
+		"level":         level.syslog(),
+		"_level_name":   level.String(),
+	}
+	if data.Hostname != "" {
+		doc["host"] = data.Hostname
+	}
+	switch {
+	case data.FullMessage != "":
+		doc["full_message"] = data.FullMessage
+	case multiline:
+		doc["full_message"] = data.Message
+	}
+
+	fields := map[string]string{
+		"_appname":      data.AppName,
+		"_ip_address":   data.IPAddress,
+		"_tr_id":        data.TransactionID,
+		"_channel":      data.Channel,
+		"_bank_code":    data.BankCode,
+		"_reference_id": data.ReferenceID,
+		"_rrn":          data.RRN,
+		"_publish_id":   data.PublishID,
+		"_cf_trid":      data.CFTrID,
+		"_device_info":  data.DeviceInfo,
+		"_param_a":      data.ParamA,
+		"_param_b":      data.ParamB,
+		"_param_c":      data.ParamC,
+	}
+	for key, value := range fields {
+		if value != "" {
+			doc[key] = value
+		}
+	}
+
+	for name, value := range data.Fields {
+		if key := fieldKey(name); key != "" {
+			doc[key] = gelfValue(value)
+		}
+	}
+	for key, value := range data.extra {
+		doc[key] = value
+	}
+
+	l.renameFields(doc)
+	return doc
+}
+
+// gelfValue converts a custom field value to one GELF accepts. Numbers and
+// booleans are kept so Graylog can aggregate them. GELF has no nested
+// values, so times, errors and Stringers become their string form, byte
+// slices base64, and other values such as maps, slices and structs their
+// JSON encoding.
+func gelfValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, string, bool, json.Number,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return v
+	case time.Duration:
+		return int64(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value // left for json.Marshal to fail on, see fallbackGELF
+	}
+	return string(encoded)
+}
+
+// stringifyUnencodable replaces the values of doc that can't be encoded as
+// JSON, such as channels and funcs, with their fmt form, so the local output
+// can still write a message whose GELF encoding failed
+func stringifyUnencodable(doc map[string]interface{}) {
+	for key, value := range doc {
+		if _, err := json.Marshal(value); err != nil {
+			doc[key] = fmt.Sprint(value)
+		}
+	}
+}
+
+// fallbackGELF encodes the core fields of doc, which always marshal, plus a
+// _marshal_error field. It is used when doc's custom fields can't be encoded.
+func fallbackGELF(doc map[string]interface{}, err error) []byte {
+	fallback := map[string]interface{}{"_marshal_error": err.Error()}
+	for _, key := range []string{"version", "host", "short_message", "full_message", "timestamp", "level", "_level_name", "_appname"} {
+		if value, ok := doc[key]; ok {
+			fallback[key] = value
+		}
+	}
+	jsonData, _ := marshalGELF(fallback)
+	return jsonData
+}
+
+// maxMessageBytes returns the effective MaxMessageBytes, or zero for no limit
+func (l *Logger) maxMessageBytes() int {
+	switch {
+	case l.MaxMessageBytes < 0:
+		return 0
+	case l.MaxMessageBytes == 0:
+		return DefaultMaxMessageBytes
+	}
+	return l.MaxMessageBytes
+}
+
+// truncateGELF shrinks doc until it encodes to at most limit bytes by cutting
+// its longest string fields, the message or a custom field, and marks it
+// with _truncated. If only short fields are left it gives up and returns the
+// smallest encoding it got.
+func truncateGELF(doc map[string]interface{}, limit int) ([]byte, error) {
+	doc["_truncated"] = true
+	for {
+		jsonData, err := marshalGELF(doc)
+		if err != nil || len(jsonData) <= limit {
+			return jsonData, err
+		}
+
+		longest, length := "", 0
+		for key, value := range doc {
+			if s, ok := value.(string); ok && len(s) > length && truncatable(key) {
+				longest, length = key, len(s)
+			}
+		}
+		if length == 0 {
+			return jsonData, nil
+		}
+
+		// Escaping makes the encoded string at least as long as the raw one,
+		// so cutting the excess always makes progress
+		s := doc[longest].(string)
+		cut := max(0, len(s)-(len(jsonData)-limit))
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		// Drop a field cut to nothing, except the required short_message
+		if cut == 0 && longest != "short_message" {
+			delete(doc, longest)
+		} else {
+			doc[longest] = s[:cut]
+		}
+	}
+}
+
+// truncatable reports whether truncateGELF may cut the field: the message
+// and any additional field other than the ones identifying the entry
+func truncatable(key string) bool {
+	switch key {
+	case "short_message", "full_message":
+		return true
+	case "_level_name", "_appname", "_ip_address":
+		return false
+	}
+	return strings.HasPrefix(key, "_")
+}
@@ -0,0 +1,233 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// testDoc returns a GELF document like the ones Log builds
+func testDoc() map[string]interface{} {
+	return map[string]interface{}{
+		"version":       "1.1",
+		"host":          "web-1",
+		"short_message": "payment accepted",
+		"timestamp":     1700000000.123,
+		"level":         6,
+		"_appname":      "payments",
+		"_tr_id":        "4bf92f3577b34da6",
+		"_amount":       42.5,
+	}
+}
+
+func TestMarshalGELF(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  map[string]interface{}
+		want string
+	}{
+		{"spec fields first", testDoc(), `{"version":"1.1","host":"web-1","short_message":"payment accepted","timestamp":1700000000.123,"level":6,"_amount":42.5,"_appname":"payments","_tr_id":"4bf92f3577b34da6"}`},
+		{"escaped like json.Marshal", map[string]interface{}{"short_message": "<a & b>"}, `{"short_message":"\u003ca \u0026 b\u003e"}`},
+		{"empty", map[string]interface{}{}, `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := marshalGELF(tt.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+			if !json.Valid(got) {
+				t.Errorf("invalid JSON %s", got)
+			}
+			// sendTCP relies on this newline
+			if cap(got) <= len(got) || got[:len(got)+1][len(got)] != '\n' {
+				t.Error("no newline past the length")
+			}
+		})
+	}
+}
+
+func TestMarshalGELFMatchesJSON(t *testing.T) {
+	values := []interface{}{
+		"plain", "", "quote \" and \\", "<tag> & more", "tab\t", "héllo", "\u2028",
+		0, -7, 1 << 40, 0.0, 1.5, -2.25, 1e-7, 1e21, 123456789.123456, 1700000000.123,
+		true, nil, []string{"a"}, map[string]int{"b": 1}, []byte("xyz"),
+	}
+	for _, v := range values {
+		want, err := json.Marshal(map[string]interface{}{"short_message": v})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := marshalGELF(map[string]interface{}{"short_message": v})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%#v: got %s, want %s", v, got, want)
+		}
+	}
+}
+
+func TestTruncateGELF(t *testing.T) {
+	// The size of testDoc once marked, which even an empty extra field
+	// makes too large
+	marked := testDoc()
+	marked["_truncated"] = true
+	base, _ := marshalGELF(marked)
+
+	tests := []struct {
+		name  string
+		doc   func() map[string]interface{}
+		limit int
+		check func(t *testing.T, doc map[string]interface{})
+	}{
+		{
+			"fits already",
+			testDoc,
+			1000,
+			func(t *testing.T, doc map[string]interface{}) {
+				if doc["short_message"] != "payment accepted" {
+					t.Errorf("short_message cut to %q", doc["short_message"])
+				}
+			},
+		},
+		{
+			"longest field cut first",
+			func() map[string]interface{} {
+				doc := testDoc()
+				doc["full_message"] = strings.Repeat("x", 1000)
+				doc["_note"] = strings.Repeat("y", 100)
+				return doc
+			},
+			400,
+			func(t *testing.T, doc map[string]interface{}) {
+				if doc["_note"] != strings.Repeat("y", 100) {
+					t.Errorf("_note cut to %q", doc["_note"])
+				}
+			},
+		},
+		{
+			"utf-8 kept whole",
+			func() map[string]interface{} {
+				doc := testDoc()
+				doc["full_message"] = strings.Repeat("é€😀", 200)
+				return doc
+			},
+			300,
+			nil,
+		},
+		{
+			"field cut to nothing dropped",
+			func() map[string]interface{} {
+				doc := testDoc()
+				doc["_note"] = strings.Repeat("y", 100)
+				return doc
+			},
+			len(base),
+			func(t *testing.T, doc map[string]interface{}) {
+				if _, ok := doc["_note"]; ok {
+					t.Errorf("_note kept as %q", doc["_note"])
+				}
+			},
+		},
+		{
+			"identifying fields kept",
+			func() map[string]interface{} {
+				doc := testDoc()
+				doc["_appname"] = strings.Repeat("a", 300)
+				doc["full_message"] = strings.Repeat("x", 300)
+				return doc
+			},
+			450,
+			func(t *testing.T, doc map[string]interface{}) {
+				if doc["_appname"] != strings.Repeat("a", 300) {
+					t.Errorf("_appname cut to %q", doc["_appname"])
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := tt.doc()
+			got, err := truncateGELF(doc, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) > tt.limit {
+				t.Errorf("%d bytes, want at most %d", len(got), tt.limit)
+			}
+			var decoded map[string]interface{}
+			if err := json.Unmarshal(got, &decoded); err != nil {
+				t.Fatalf("invalid JSON %s: %v", got, err)
+			}
+			if decoded["_truncated"] != true {
+				t.Error("no _truncated marker")
+			}
+			for key, value := range doc {
+				if s, ok := value.(string); ok && !utf8.ValidString(s) {
+					t.Errorf("%s cut mid rune: %q", key, s)
+				}
+			}
+			if _, ok := decoded["short_message"]; !ok {
+				t.Error("short_message dropped")
+			}
+			if tt.check != nil {
+				tt.check(t, doc)
+			}
+		})
+	}
+}
+
+func TestTruncateGELFGivesUp(t *testing.T) {
+	// Only fields truncateGELF may not cut are left, so it returns what it has
+	doc := testDoc()
+	doc["short_message"] = ""
+	doc["_appname"] = strings.Repeat("a", 300)
+	got, err := truncateGELF(doc, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(got) || len(got) <= 100 {
+		t.Errorf("got %d bytes %s, want the valid oversized message", len(got), got)
+	}
+}
+
+func BenchmarkMarshalGELF(b *testing.B) {
+	doc := testDoc()
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := marshalGELF(doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalGELFParallel(b *testing.B) {
+	doc := testDoc()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := marshalGELF(doc); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+// BenchmarkMarshalGELFLarge shows a message above maxPooledBuffer, whose
+// buffer is not pooled
+func BenchmarkMarshalGELFLarge(b *testing.B) {
+	doc := testDoc()
+	doc["full_message"] = string(bytes.Repeat([]byte("x"), 2*maxPooledBuffer))
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := marshalGELF(doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
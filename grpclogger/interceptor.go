@@ -0,0 +1,116 @@
+// Package grpclogger logs RPCs served by a gRPC server to a logger.Logger
+package grpclogger
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/sahuprasantakumar975/logger"
+)
+
+// RequestIDKey is the incoming metadata key whose value becomes the
+// message's TransactionID
+const RequestIDKey = "x-request-id"
+
+// UnaryServerInterceptor returns an interceptor that logs every unary RPC
+// once it has been handled, e.g. "/orders.Orders/Get OK", with the method,
+// status code, client IP and duration (_duration_ms) as fields. RPCs that
+// fail with a server-side code such as Internal or Unavailable are logged
+// at LevelError, other failures at LevelWarn and the rest at LevelInfo.
+func UnaryServerInterceptor(l *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logRPC(l, ctx, start, info.FullMethod, "unary", err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that logs every streaming
+// RPC once it has finished, like UnaryServerInterceptor
+func StreamServerInterceptor(l *logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logRPC(l, ss.Context(), start, info.FullMethod, streamType(info), err)
+		return err
+	}
+}
+
+// logRPC logs a finished RPC
+func logRPC(l *logger.Logger, ctx context.Context, start time.Time, method, rpcType string, err error) {
+	code := status.Code(err)
+	fields := map[string]interface{}{
+		"method":   method,
+		"code":     code.String(),
+		"rpc_type": rpcType,
+	}
+	if ip := clientIP(ctx); ip != "" {
+		fields["client_ip"] = ip
+	}
+	if err != nil {
+		fields["error"] = status.Convert(err).Message()
+	}
+	data := logger.LogData{
+		TransactionID: requestID(ctx),
+		Fields:        fields,
+	}
+
+	message := fmt.Sprintf("%s %s", method, code)
+	l.LogTimed(start, level(code), message, data)
+}
+
+// level picks the log level for an RPC's status code
+func level(code codes.Code) logger.Level {
+	switch code {
+	case codes.OK:
+		return logger.LevelInfo
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		return logger.LevelError
+	default:
+		return logger.LevelWarn
+	}
+}
+
+// requestID returns the first RequestIDKey value of the incoming metadata
+func requestID(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, RequestIDKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// clientIP returns the IP of the peer that sent the RPC, if known
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	addr := p.Addr.String()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// streamType describes a streaming RPC by which sides stream
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info.IsClientStream && info.IsServerStream:
+		return "bidi_stream"
+	case info.IsClientStream:
+		return "client_stream"
+	default:
+		return "server_stream"
+	}
+}
@@ -0,0 +1,120 @@
+package grpclogger
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/sahuprasantakumar975/logger"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	withPeer := func(ctx context.Context) context.Context {
+		return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 51234}})
+	}
+	tests := []struct {
+		name        string
+		ctx         func(context.Context) context.Context
+		err         error
+		wantMessage string
+		wantLevel   logger.Level
+		wantFields  map[string]interface{}
+	}{
+		{
+			"ok",
+			withPeer,
+			nil,
+			"/orders.Orders/Get OK", logger.LevelInfo,
+			map[string]interface{}{"_code": "OK", "_client_ip": "192.0.2.1", "_tr_id": "req-1"},
+		},
+		{
+			"client error",
+			withPeer,
+			status.Error(codes.NotFound, "no order 7"),
+			"/orders.Orders/Get NotFound", logger.LevelWarn,
+			map[string]interface{}{"_code": "NotFound", "_error": "no order 7"},
+		},
+		{
+			"server error",
+			withPeer,
+			status.Error(codes.Unavailable, "db down"),
+			"/orders.Orders/Get Unavailable", logger.LevelError,
+			map[string]interface{}{"_code": "Unavailable", "_error": "db down"},
+		},
+		{
+			"plain error",
+			withPeer,
+			errors.New("boom"),
+			"/orders.Orders/Get Unknown", logger.LevelError,
+			map[string]interface{}{"_code": "Unknown", "_error": "boom"},
+		},
+		{
+			"no peer",
+			func(ctx context.Context) context.Context { return ctx },
+			nil,
+			"/orders.Orders/Get OK", logger.LevelInfo,
+			map[string]interface{}{"_client_ip": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, sink := logger.NewTestLogger()
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDKey, "req-1"))
+			ctx = tt.ctx(ctx)
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				time.Sleep(10 * time.Millisecond)
+				return "resp", tt.err
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}
+
+			resp, err := UnaryServerInterceptor(l)(ctx, "req", info, handler)
+			if resp != "resp" || err != tt.err {
+				t.Errorf("got %v, %v, want the handler's resp, %v", resp, err, tt.err)
+			}
+
+			messages := sink.Messages()
+			if len(messages) != 1 {
+				t.Fatalf("%d messages logged, want 1", len(messages))
+			}
+			m := messages[0]
+			if m["short_message"] != tt.wantMessage || m["_level_name"] != tt.wantLevel.String() {
+				t.Errorf("got %q at %v, want %q at %v", m["short_message"], m["_level_name"], tt.wantMessage, tt.wantLevel)
+			}
+			if m["_method"] != "/orders.Orders/Get" || m["_rpc_type"] != "unary" {
+				t.Errorf("_method = %v, _rpc_type = %v", m["_method"], m["_rpc_type"])
+			}
+			for key, want := range tt.wantFields {
+				if m[key] != want {
+					t.Errorf("%s = %v, want %v", key, m[key], want)
+				}
+			}
+			if duration, ok := m["_duration_ms"].(float64); !ok || duration < 10 {
+				t.Errorf("_duration_ms = %v, want at least 10", m["_duration_ms"])
+			}
+		})
+	}
+}
+
+func TestStreamType(t *testing.T) {
+	tests := []struct {
+		info *grpc.StreamServerInfo
+		want string
+	}{
+		{&grpc.StreamServerInfo{IsClientStream: true, IsServerStream: true}, "bidi_stream"},
+		{&grpc.StreamServerInfo{IsClientStream: true}, "client_stream"},
+		{&grpc.StreamServerInfo{IsServerStream: true}, "server_stream"},
+	}
+	for _, tt := range tests {
+		if got := streamType(tt.info); got != tt.want {
+			t.Errorf("streamType(%+v) = %q, want %q", tt.info, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// gelfHTTPPath is where Graylog's GELF HTTP input accepts messages
+const gelfHTTPPath = "/gelf"
+
+// isHTTP reports whether protocol is one of the HTTP transports
+func isHTTP(protocol string) bool {
+	return protocol == "http" || protocol == "https"
+}
+
+// newHTTPClient builds the default client for the HTTP transports
+func (l *Logger) newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = l.tlsConfig()
+	if l.DialFunc != nil {
+		transport.DialContext = l.DialFunc
+		transport.Proxy = nil // DialFunc decides the route
+	}
+	return &http.Client{Timeout: l.Timeout, Transport: transport}
+}
+
+// sendHTTP POSTs a GELF document, already compressed with algorithm, to
+// Graylog's HTTP input
+func (l *Logger) sendHTTP(ctx context.Context, body []byte, algorithm string) error {
+	endpoint := url.URL{Scheme: l.Protocol, Host: l.address(), Path: gelfHTTPPath}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch algorithm {
+	case CompressionGzip:
+		req.Header.Set("Content-Encoding", "gzip")
+	case CompressionZlib:
+		req.Header.Set("Content-Encoding", "deflate")
+	}
+
+	resp, err := l.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) // drain so the connection is reused
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected HTTP status %s", resp.Status)
+	}
+	return nil
+}
+
+// pingHTTP sends a HEAD request to Graylog's HTTP input. Any response short
+// of a server error shows the input is up, as it may not allow HEAD.
+func (l *Logger) pingHTTP(ctx context.Context) error {
+	endpoint := url.URL{Scheme: l.Protocol, Host: l.address(), Path: gelfHTTPPath}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := l.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("unexpected HTTP status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Package httplogger logs HTTP requests served by a net/http handler to a
+// logger.Logger
+package httplogger
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sahuprasantakumar975/logger"
+)
+
+// RequestIDHeader is the request header whose value becomes the message's
+// TransactionID
+const RequestIDHeader = "X-Request-ID"
+
+// Middleware returns middleware that logs every request once it has been
+// served, e.g. "GET /orders 200", with the method, path, status, response
+// size, client IP and duration (_duration_ms) as fields. Requests that
+// fail with a 5xx status are logged at LevelError, 4xx at LevelWarn and the
+// rest at LevelInfo. Handlers can get a Logger that tags their messages
+// with the request's TransactionID from logger.LoggerFromContext.
+func Middleware(l *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			scoped := l.WithFields(logger.LogData{TransactionID: r.Header.Get(RequestIDHeader)})
+			next.ServeHTTP(rec, r.WithContext(logger.ContextWithLogger(r.Context(), scoped)))
+
+			fields := map[string]interface{}{
+				"method":    r.Method,
+				"path":      r.URL.Path,
+				"status":    rec.status,
+				"bytes":     rec.bytes,
+				"client_ip": clientIP(r),
+			}
+			if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+				fields["forwarded_for"] = forwarded
+			}
+			data := logger.LogData{
+				TransactionID: r.Header.Get(RequestIDHeader),
+				Fields:        fields,
+			}
+
+			message := fmt.Sprintf("%s %s %d", r.Method, r.URL.Path, rec.status)
+			scoped.LogTimed(start, level(rec.status), message, data)
+		})
+	}
+}
+
+// level picks the log level for a response status
+func level(status int) logger.Level {
+	switch {
+	case status >= 500:
+		return logger.LevelError
+	case status >= 400:
+		return logger.LevelWarn
+	default:
+		return logger.LevelInfo
+	}
+}
+
+// clientIP returns the IP of the peer that sent r. Proxies' X-Forwarded-For
+// is logged separately, as clients can set it to anything.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// statusRecorder is a ResponseWriter that remembers the status and counts
+// the body bytes written
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter,
+// e.g. to flush or hijack
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
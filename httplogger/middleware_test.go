@@ -0,0 +1,116 @@
+package httplogger
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sahuprasantakumar975/logger"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		wantMessage string
+		wantLevel   logger.Level
+		wantStatus  float64
+		wantBytes   float64
+		minDuration float64 // in milliseconds
+	}{
+		{
+			"implicit 200",
+			func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "hello") },
+			"GET /orders/7 200", logger.LevelInfo, 200, 5, 0,
+		},
+		{
+			"not found",
+			func(w http.ResponseWriter, r *http.Request) { http.NotFound(w, r) },
+			"GET /orders/7 404", logger.LevelWarn, 404, 19, 0,
+		},
+		{
+			"server error",
+			func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) },
+			"GET /orders/7 502", logger.LevelError, 502, 0, 0,
+		},
+		{
+			"first status wins",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			"GET /orders/7 201", logger.LevelInfo, 201, 0, 0,
+		},
+		{
+			"status after body ignored",
+			func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "ok")
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			"GET /orders/7 200", logger.LevelInfo, 200, 2, 0,
+		},
+		{
+			"slow handler",
+			func(w http.ResponseWriter, r *http.Request) { time.Sleep(20 * time.Millisecond) },
+			"GET /orders/7 200", logger.LevelInfo, 200, 0, 20,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, sink := logger.NewTestLogger()
+			r := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+			r.RemoteAddr = "192.0.2.1:51234"
+			r.Header.Set(RequestIDHeader, "req-1")
+			r.Header.Set("X-Forwarded-For", "198.51.100.9")
+
+			start := time.Now()
+			Middleware(l)(tt.handler).ServeHTTP(httptest.NewRecorder(), r)
+			elapsed := time.Since(start)
+
+			messages := sink.Messages()
+			if len(messages) != 1 {
+				t.Fatalf("%d messages logged, want 1", len(messages))
+			}
+			m := messages[0]
+			for key, want := range map[string]interface{}{
+				"short_message":  tt.wantMessage,
+				"_level_name":    tt.wantLevel.String(),
+				"_status":        tt.wantStatus,
+				"_bytes":         tt.wantBytes,
+				"_method":        "GET",
+				"_path":          "/orders/7",
+				"_client_ip":     "192.0.2.1",
+				"_forwarded_for": "198.51.100.9",
+				"_tr_id":         "req-1",
+			} {
+				if m[key] != want {
+					t.Errorf("%s = %v, want %v", key, m[key], want)
+				}
+			}
+			duration, ok := m["_duration_ms"].(float64)
+			if most := float64(elapsed) / float64(time.Millisecond); !ok || duration < tt.minDuration || duration > most {
+				t.Errorf("_duration_ms = %v, want between %v and %v", m["_duration_ms"], tt.minDuration, most)
+			}
+		})
+	}
+}
+
+func TestMiddlewareContextLogger(t *testing.T) {
+	l, sink := logger.NewTestLogger()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		logger.LoggerFromContext(r.Context()).Info("inside", logger.LogData{})
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "req-2")
+	Middleware(l)(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), r)
+
+	messages := sink.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("%d messages logged, want 2", len(messages))
+	}
+	if m := messages[0]; m["short_message"] != "inside" || m["_tr_id"] != "req-2" {
+		t.Errorf("handler message %v, want short_message inside and _tr_id req-2", m)
+	}
+}
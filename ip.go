@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// ipCache holds the local IP so it isn't looked up on every log
+type ipCache struct {
+	mu       sync.Mutex
+	ip       string
+	resolved time.Time
+	refresh  time.Duration // re-resolve after this long; zero means never
+}
+
+// get returns the cached IP, re-resolving it if the refresh interval passed
+func (c *ipCache) get() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ip == "" || (c.refresh > 0 && time.Since(c.resolved) >= c.refresh) {
+		c.updateLocked()
+	}
+	return c.ip
+}
+
+// update re-resolves the IP and returns it
+func (c *ipCache) update() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updateLocked()
+	return c.ip
+}
+
+func (c *ipCache) updateLocked() {
+	c.ip = GetLocalIP()
+	c.resolved = time.Now()
+}
+
+// Public addresses used to find the outbound interface, IPv4 first. Nothing
+// is sent to them; dialing UDP only selects a route.
+var outboundProbeAddrs = []string{"8.8.8.8:80", "[2001:4860:4860::8888]:80"}
+
+// GetLocalIP retrieves the local machine's IP address. It prefers the source
+// address of the default route, which is what Graylog sees, and falls back
+// to the first non-loopback interface address. IPv4 is preferred over IPv6.
+func GetLocalIP() string {
+	for _, probe := range outboundProbeAddrs {
+		if ip := outboundIP(probe); ip != "" {
+			return ip
+		}
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "Unknown"
+	}
+	ipv6 := ""
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+			if ipNet.IP.To4() != nil {
+				return ipNet.IP.String()
+			}
+			if ipv6 == "" && ipNet.IP.IsGlobalUnicast() {
+				ipv6 = ipNet.IP.String()
+			}
+		}
+	}
+	if ipv6 != "" {
+		return ipv6
+	}
+	return "Unknown"
+}
+
+// outboundIP returns the local address the OS would use to reach probe, or
+// "" if there is no route
+func outboundIP(probe string) string {
+	conn, err := net.Dial("udp", probe)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok || addr.IP.IsLoopback() || addr.IP.IsUnspecified() {
+		return ""
+	}
+	return addr.IP.String()
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Level is the severity of a log message
+type Level int
+
+// newLevelVar returns a level that can be changed while it is being read
+func newLevelVar(level Level) *atomic.Int32 {
+	v := &atomic.Int32{}
+	v.Store(int32(level))
+	return v
+}
+
+// Log levels, from most to least verbose
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// String returns the level name, e.g. "INFO"
+func (lv Level) String() string {
+	switch lv {
+	case LevelTrace:
+		return "TRACE"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	case LevelFatal:
+		return "FATAL"
+	default:
+		return fmt.Sprintf("Level(%d)", int(lv))
+	}
+}
+
+// ParseLevel parses a level name such as "info" or "WARN", ignoring case.
+// "warning" is accepted for LevelWarn.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "TRACE":
+		return LevelTrace, nil
+	case "DEBUG":
+		return LevelDebug, nil
+	case "INFO":
+		return LevelInfo, nil
+	case "WARN", "WARNING":
+		return LevelWarn, nil
+	case "ERROR":
+		return LevelError, nil
+	case "FATAL":
+		return LevelFatal, nil
+	default:
+		return 0, fmt.Errorf("graylog: unknown level %q", name)
+	}
+}
+
+// logrusLevel maps the level to its logrus equivalent. Unknown levels are
+// logged as warnings.
+func (lv Level) logrusLevel() logrus.Level {
+	switch lv {
+	case LevelTrace:
+		return logrus.TraceLevel
+	case LevelDebug:
+		return logrus.DebugLevel
+	case LevelInfo:
+		return logrus.InfoLevel
+	case LevelError:
+		return logrus.ErrorLevel
+	case LevelFatal:
+		return logrus.FatalLevel
+	default:
+		return logrus.WarnLevel
+	}
+}
+
+// syslog maps the level to its syslog severity, as GELF expects
+func (lv Level) syslog() int {
+	switch lv {
+	case LevelTrace, LevelDebug:
+		return 7
+	case LevelInfo:
+		return 6
+	case LevelError:
+		return 3
+	case LevelFatal:
+		return 2
+	default:
+		return 4
+	}
+}
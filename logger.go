@@ -1,20 +1,39 @@
 package logger
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-// LogData represents the structured log format
+// ErrNoAppName is returned by Log when RequireAppName is set and a message
+// has no app name
+var ErrNoAppName = errors.New("graylog: app name is not set")
+
+// LogData represents the structured log format. Log sends it to Graylog as a
+// GELF document; Timestamp is not used there, as GELF carries its own.
+//
+// The message's first line is sent as the GELF short_message, which
+// Graylog shows in its message list, and a multi-line message as a whole as
+// full_message. FullMessage, if set, is sent as full_message instead, e.g.
+// a stack trace or request dump behind a one-line message.
 type LogData struct {
 	Timestamp     string `json:"timestamp"`
 	Level         string `json:"level"`
 	Message       string `json:"message,omitempty"`
+	FullMessage   string `json:"full_message,omitempty"`
 	IPAddress     string `json:"ip_address,omitempty"`
 	AppName       string `json:"appname"`
 	Hostname      string `json:"hostname,omitempty"`
@@ -29,128 +48,796 @@ type LogData struct {
 	ParamA        string `json:"param_a,omitempty"`
 	ParamB        string `json:"param_b,omitempty"`
 	ParamC        string `json:"param_c,omitempty"`
+
+	// Fields holds arbitrary custom fields. They are emitted as GELF
+	// additional fields, i.e. with a leading underscore. Numbers and
+	// booleans stay numbers and booleans, so Graylog can aggregate them, and
+	// durations are sent in nanoseconds. As GELF has no nested values,
+	// times, errors and Stringers are sent as strings, byte slices as
+	// base64, and maps, other slices and structs as their JSON encoding.
+	Fields map[string]interface{} `json:"-"`
+
+	// extra holds additional fields set by the logger itself, keyed by
+	// their final GELF name
+	extra map[string]interface{}
+}
+
+// SetBinary sets the custom field name to b, sent base64-encoded (standard
+// encoding, padded) so raw bytes such as device payloads arrive intact
+func (d *LogData) SetBinary(name string, b []byte) {
+	if d.Fields == nil {
+		d.Fields = map[string]interface{}{}
+	}
+	d.Fields[name] = b
 }
 
-// Logger struct
+// setExtra adds a logger-generated GELF field
+func (d *LogData) setExtra(key string, value interface{}) {
+	if d.extra == nil {
+		d.extra = map[string]interface{}{}
+	}
+	d.extra[key] = value
+}
+
+// Logger sends log messages to Graylog and writes them locally.
+//
+// A Logger is safe for concurrent use once constructed: the Graylog
+// connection is held by one send at a time, so messages are never
+// interleaved on the wire, and the local output, counters and caches are
+// guarded. Loggers derived with WithFields share all of this. Callbacks
+// such as OnError may be called from several goroutines at once.
+//
+// The exported fields are configuration. Set them before the Logger is
+// shared; changing them while messages are being logged is a data race.
+// The level is the exception: SetLevel may be called at any time.
 type Logger struct {
 	logger      *logrus.Logger
-	GraylogHost string
-	GraylogPort string
-	Protocol    string // "udp" or "tcp"
+	GraylogHost string        // host name or IP; the socket path for "unix" and "unixgram"
+	GraylogPort string        // unused for "unix" and "unixgram"
+	Protocol    string        // "udp" (default), "tcp", "http", "https", "unix" or "unixgram", in any case
+	Compression string        // "none" (default), "gzip" or "zlib", in any case; applies to datagrams and HTTP
+	AppName     string        // used when LogData.AppName is empty
+	Timeout     time.Duration // bounds each send, dial, write and retries included; zero means no limit
+
+	// Format is what messages are sent as, in any case: FormatGELF, the
+	// default, or FormatRFC5424 for syslog collectors that don't speak
+	// GELF. RFC 5424 messages carry the additional fields as structured
+	// data and go over the same UDP, TCP or Unix socket transports,
+	// uncompressed; on streams they are framed by octet counting and never
+	// batched.
+	Format string
+
+	// CompressionThreshold is the payload size in bytes below which
+	// messages are sent uncompressed, as compressing small ones costs CPU
+	// and may not shrink them. Graylog detects either form.
+	CompressionThreshold int
+
+	// WriteTimeout, if set, bounds each write instead of Timeout. With
+	// neither set, TCP writes are still bounded by 10s, so a send on a
+	// half-open connection fails and reconnects rather than hanging.
+	WriteTimeout time.Duration
+	// KeepAlive is the TCP keep-alive interval; zero uses Go's default of
+	// 15s and a negative value disables keep-alives
+	KeepAlive time.Duration
+	// DialFunc, if set, opens connections to Graylog instead of a
+	// net.Dialer, e.g. to go through a SOCKS5 proxy with the DialContext
+	// of a golang.org/x/net/proxy dialer, or to use in-memory connections
+	// in tests. Timeout bounds the call and TLS is layered on top. It is
+	// used by the default HTTP client too. SOCKS and HTTP proxies usually
+	// carry only TCP, so UDP needs a direct route.
+	DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// MaxMessageBytes caps the encoded size of a message. Larger ones have
+	// their longest string fields truncated and are marked with _truncated,
+	// so something useful still arrives. Zero means DefaultMaxMessageBytes,
+	// the UDP chunking limit; a negative value disables the cap.
+	MaxMessageBytes int
+	// MaxFieldBytes caps the size of every string additional field, so one
+	// call site dumping blobs can't bloat the Graylog index. FieldLimits
+	// sets caps per field, by name as sent without the leading underscore,
+	// e.g. "param_c"; a negative one exempts the field. Cut fields are
+	// listed in _field_truncated and reported to OnError. Zero means no cap.
+	MaxFieldBytes int
+	FieldLimits   map[string]int
+
+	// FieldNameMap renames additional fields to match an existing Graylog
+	// schema, e.g. {"tr_id": "transaction_id"}. Keys are field names as
+	// emitted, without the leading underscore; custom fields are matched
+	// after any "custom_" prefix is added. The GELF core fields can't be
+	// renamed.
+	FieldNameMap map[string]string
+
+	// Validate checks each message before it is sent: GELF's required
+	// fields, a Unix or TimestampFormat timestamp, a valid level, an app
+	// name, and additional field names and types. Problems are returned by
+	// Log as a *ValidationError and passed to OnError; the message is still
+	// sent, so nothing is lost while the caller is fixed.
+	Validate bool
+
+	// RequireAppName makes Log return ErrNoAppName for a message with no
+	// app name from either LogData or AppName. The message is still
+	// logged, so nothing is lost while the caller is fixed.
+	RequireAppName bool
+
+	// Destinations are extra Graylog endpoints that receive every message,
+	// or those at their MinLevel or above, alongside GraylogHost. They
+	// share all other settings.
+	Destinations []Destination
+
+	// DisableRemote skips Graylog entirely; messages are only written locally
+	DisableRemote bool
+	// DisableLocal skips the local output; messages are only sent to Graylog
+	DisableLocal bool
+	// DisableHostname and DisableIPLookup stop the Logger from filling in
+	// LogData.Hostname and LogData.IPAddress with the detected hostname and
+	// local IP, where those mislead, e.g. container addresses and pod IDs.
+	// The fields are then only sent when set per message. Graylog rejects
+	// messages without a host, so set Hostname some other way.
+	DisableHostname bool
+	DisableIPLookup bool
+	// EmergencyOutput receives messages the local output fails to write,
+	// e.g. because stdout is a closed pipe, so they aren't lost; os.Stderr
+	// by default. A write that blocks rather than fails can't be detected.
+	EmergencyOutput io.Writer
+	// LocalFormat is how messages are written locally, independently of
+	// the GELF sent to Graylog; LocalJSON by default
+	LocalFormat LocalFormat
+	// DryRun goes through the whole send path, compression and framing
+	// included, but instead of transmitting, passes what would be sent to
+	// OnDryRun: the protocol, the address and the frames, i.e. each
+	// datagram or GELF chunk, the newline-terminated TCP message, or the
+	// HTTP body. Nothing is dialed.
+	DryRun   bool
+	OnDryRun func(protocol, address string, frames [][]byte)
+
+	// MaxRetries is how many times a failed send is retried. Each retry
+	// waits an exponentially growing, jittered delay starting at
+	// RetryBackoff (100ms if unset), and stops early when the context ends.
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// ReconnectBuffer, if above zero, makes a send that fails on a TCP, UDP
+	// or Unix socket connection buffer the message instead, up to that
+	// many, and reconnect in the background with backoff. Until the buffer
+	// has been sent, new messages are buffered behind it and Log reports
+	// success. ReconnectOverflow says which message is dropped when the
+	// buffer is full: OverflowDropOldest, or by default the new one.
+	ReconnectBuffer   int
+	ReconnectOverflow OverflowPolicy
+
+	// BreakerThreshold, if above zero, opens a circuit breaker after that
+	// many consecutive failed sends: for BreakerCooldown (30s if unset)
+	// sends fail at once with ErrCircuitOpen, or go to the spool, instead of
+	// waiting on a dead endpoint. Then one send probes whether it is back.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// SpoolPath, if set, is a file where messages that could not be sent
+	// are kept, one length-prefixed record per message, and replayed once
+	// Graylog is reachable. It survives restarts, giving at-least-once
+	// delivery.
+	// SpoolMaxBytes caps its size; messages that don't fit are dropped.
+	SpoolPath     string
+	SpoolMaxBytes int64
+
+	// NullDelimiter ends TCP and Unix stream messages with a null byte,
+	// as the GELF spec says, instead of the newline used by default. Use it
+	// for Graylog inputs that require null-byte framing; newline framing
+	// must be enabled on inputs that don't.
+	NullDelimiter bool
+
+	// BatchSize is how many messages an async TCP Logger joins into one
+	// write; FlushInterval bounds how long a partial batch waits, 1s by
+	// default, with a little jitter so instances started together don't
+	// flush together. Other protocols send messages one by one.
+	BatchSize     int
+	FlushInterval time.Duration
+	// BlockTimeout bounds how long Log waits for room in a full async
+	// buffer with OverflowBlock before dropping the message; zero waits
+	// until the context is done
+	BlockTimeout time.Duration
+
+	ExitCode int // process exit code used by Fatal, 1 by default
+
+	// ReportCaller adds the file, line and function that logged each
+	// message as _file, _line and _func. It costs a stack walk per message.
+	ReportCaller bool
+	// Stacktrace adds the goroutine's stack trace as _stacktrace to messages
+	// at StacktraceLevel or above
+	Stacktrace      bool
+	StacktraceLevel Level
+	// ProcessMetadata adds the process ID, Go version and goroutine count
+	// as _pid, _go_version and _num_goroutine to every message
+	ProcessMetadata bool
+	// AppVersion, if set, is added to every message as _app_version
+	AppVersion string
+
+	// TimeFunc returns the time stamped on each message, time.Now by
+	// default; tests can pin it for deterministic output
+	TimeFunc func() time.Time
+	// TimestampFormat is TimestampEpoch (the default, as GELF requires) or a
+	// time layout such as time.RFC3339 for collectors expecting strings
+	TimestampFormat string
+
+	// OnError is called on the logging goroutine, or on the worker
+	// goroutine for an async Logger, with an uncompressed payload and:
+	//   - the send error, once a send fails after retries; for a batch, the
+	//     payload holds the delimited messages
+	//   - an error naming the fields cut down to FieldLimits, with the
+	//     message as sent
+	//   - a *ValidationError, with the message as sent, if Validate is set
+	//   - an error wrapping the json.Marshal failure, with the fallback
+	//     message sent instead
+	//   - ErrBufferFull, with each message a full async or reconnect
+	//     buffer dropped
+	//   - ErrClosed, with each message still buffered for reconnecting at
+	//     Close when there is no spool
+	//   - the spool error, with the payload that couldn't be spooled, or
+	//     a nil payload if reading or trimming the spool fails
+	OnError func(payload []byte, err error)
+
+	// RedactFields names fields whose values are replaced with "****",
+	// and matches of RedactPatterns are masked in every other value. Both
+	// apply to the local output and the Graylog payload.
+	RedactFields   []string
+	RedactPatterns []*regexp.Regexp
+
+	// HTTPClient sends messages for the "http" and "https" protocols. If
+	// nil, a client using Timeout and the TLS settings is created.
+	HTTPClient *http.Client
+
+	// TLSConfig enables TLS for the TCP transport and configures HTTPS;
+	// UDP is unaffected
+	TLSConfig *tls.Config
+	// TLSInsecureSkipVerify enables TLS without verifying Graylog's
+	// certificate. This is DANGEROUS: anyone on the network path can read
+	// and forge logs. Only use it in development.
+	TLSInsecureSkipVerify bool
+
+	level    *atomic.Int32 // messages below this Level are skipped
+	hostname string        // resolved once at construction
+	fields   LogData       // preset by WithDefaults and WithFields
+
+	resolveHost bool // resolve GraylogHost when validating
+	autoTrID    bool // generate missing transaction IDs, see WithTransactionIDs
+	extractors  []ContextExtractor
+	ip          *ipCache
+	limiter     *rateLimiter
+	counters    *counters
+	observers   *sendObservers
+	dedup       *deduper // nil unless WithDedup is used
+	breaker     *breaker
+	conn        *connection
+	reconnect   *reconnector // nil unless ReconnectBuffer is set
+	overrides   *overrides   // endpoints for LogVia
+	sink        *CaptureSink // set by NewTestLogger
+	endpoints   []*Logger    // one per Destination, set up at construction
+	minLevel    Level        // for a Destination endpoint, its MinLevel
+	spool       *spool       // nil unless SpoolPath is set
+	async       *asyncWorker // nil unless WithAsync is used
+
+	// Set by WithAsync, which can't return an error, so the buffer size is
+	// checked and the worker created at construction
+	useAsync    bool
+	asyncSize   int
+	asyncPolicy OverflowPolicy
+}
+
+// NewLogger initializes a new logger with the chosen protocol, matched
+// ignoring case, or UDP if it is empty. It returns an error if the protocol,
+// host or port is invalid.
+func NewLogger(graylogHost, graylogPort, protocol string) (*Logger, error) {
+	return NewLoggerWithOptions(WithGraylog(graylogHost, graylogPort, protocol))
 }
 
-// NewLogger initializes a new logger with the chosen protocol
-func NewLogger(graylogHost, graylogPort, protocol string) *Logger {
+// NewLoggerWithOptions initializes a new logger configured by opts. It
+// returns an error if the resulting configuration is invalid.
+func NewLoggerWithOptions(opts ...Option) (*Logger, error) {
 	l := logrus.New()
 	l.SetFormatter(&logrus.JSONFormatter{})
 	l.SetOutput(os.Stdout)
+	l.SetLevel(logrus.TraceLevel) // filtering is done by Logger.level
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "Unknown"
+	}
 
-	// Validate protocol
-	if protocol != "udp" && protocol != "tcp" {
-		fmt.Println("Invalid protocol! Defaulting to UDP.")
-		protocol = "udp"
+	logger := &Logger{
+		logger:    l,
+		ExitCode:  1,
+		level:     newLevelVar(LevelDebug),
+		hostname:  hostname,
+		ip:        &ipCache{},
+		limiter:   &rateLimiter{},
+		counters:  &counters{},
+		observers: &sendObservers{},
+		breaker:   &breaker{},
+		conn:      &connection{},
+		overrides: &overrides{},
+	}
+	for _, opt := range opts {
+		opt(logger)
+	}
+	logger.SetOutput(logger.logger.Out) // with the final EmergencyOutput
+	if !logger.DisableIPLookup {
+		logger.ip.update()
 	}
 
-	return &Logger{
-		logger:      l,
-		GraylogHost: graylogHost,
-		GraylogPort: graylogPort,
-		Protocol:    protocol,
+	if logger.DisableRemote {
+		return logger, nil // nothing to send, so no async worker
 	}
+
+	logger.Protocol = normalizeProtocol(logger.Protocol)
+	if logger.Protocol == "" {
+		logger.Protocol = "udp"
+	}
+	if !validProtocol(logger.Protocol) {
+		return nil, fmt.Errorf("graylog: invalid protocol %q", logger.Protocol)
+	}
+
+	if err := logger.validateAddress(); err != nil {
+		return nil, err
+	}
+	// Matched ignoring case, like the protocol
+	logger.Format = strings.ToLower(strings.TrimSpace(logger.Format))
+	logger.Compression = strings.ToLower(strings.TrimSpace(logger.Compression))
+	if err := logger.validateFormat(); err != nil {
+		return nil, err
+	}
+	if err := logger.validateCompression(); err != nil {
+		return nil, err
+	}
+	if logger.useAsync {
+		if logger.asyncSize < 1 {
+			return nil, fmt.Errorf("graylog: invalid async buffer size %d, must be at least 1", logger.asyncSize)
+		}
+		logger.async = newAsyncWorker(logger.asyncSize, logger.asyncPolicy)
+	}
+	logger.initReconnect()
+	if err := logger.setupEndpoints(); err != nil {
+		return nil, err
+	}
+	logger.connect()
+
+	if logger.SpoolPath != "" {
+		logger.spool = newSpool(logger.SpoolPath, logger.SpoolMaxBytes)
+		go logger.runSpool()
+	}
+
+	if logger.async != nil {
+		// GELF streams are delimited, so only they can batch messages
+		batchSize := 1
+		if logger.streamOnly() && !logger.syslog() {
+			batchSize = logger.BatchSize
+		}
+		flushInterval := logger.FlushInterval
+		if flushInterval <= 0 {
+			flushInterval = defaultFlushInterval
+		}
+		go logger.async.run(func(ctx context.Context, payload []byte, messages int) {
+			// There is no caller to return the error to, so without an
+			// OnError callback report it locally
+			err := logger.sendToGraylog(ctx, payload, messages)
+			if err != nil && logger.OnError == nil && !logger.DisableLocal {
+				logger.logger.WithError(err).Error("Failed to send log to Graylog")
+			}
+		}, batchSize, flushInterval, logger.delimiter())
+	}
+
+	return logger, nil
 }
 
-// Log logs a message and sends it to Graylog
-func (l *Logger) Log(level, message string, data LogData) {
-	// Automatically set timestamp, hostname, and IP dynamically
-	data.Timestamp = time.Now().UTC().Format(time.RFC3339)
-	data.Message = message
+// connect prepares the transport: an HTTP client for HTTP, otherwise an
+// eager dial. If Graylog is not reachable yet the first send retries and
+// reports any error.
+func (l *Logger) connect() {
+	if l.DryRun {
+		return
+	}
+	if isHTTP(l.Protocol) {
+		if l.HTTPClient == nil {
+			l.HTTPClient = l.newHTTPClient()
+		}
+		return
+	}
+	dial := func() (net.Conn, error) {
+		return l.dial(context.Background())
+	}
+	_ = l.conn.dial(dial)
+}
 
-	// Set dynamic hostname and IP if not already provided
+// validateAddress checks that the Graylog host is set and the port is a
+// number in range. With WithResolveHost, the host must also resolve. For
+// the Unix socket protocols the host is the socket path and there is no
+// port.
+func (l *Logger) validateAddress() error {
+	if strings.TrimSpace(l.GraylogHost) == "" {
+		return errors.New("graylog: host is empty")
+	}
+	if isUnix(l.Protocol) {
+		return nil
+	}
 
-	hostname, err := os.Hostname()
-	if err == nil {
-		data.Hostname = hostname
-	} else {
-		data.Hostname = "Unknown"
+	port, err := strconv.Atoi(l.GraylogPort)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("graylog: invalid port %q", l.GraylogPort)
 	}
 
-	data.IPAddress = GetLocalIP()
+	if l.resolveHost {
+		ctx := context.Background()
+		if l.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, l.Timeout)
+			defer cancel()
+		}
+		if _, err := net.DefaultResolver.LookupHost(ctx, l.host()); err != nil {
+			return fmt.Errorf("graylog: resolve host: %w", err)
+		}
+	}
+	return nil
+}
 
-	jsonData, _ := json.Marshal(data)
+// NewNopLogger returns a Logger that discards everything: nothing is sent to
+// Graylog and nothing is written locally. Useful in tests and CLI tools.
+func NewNopLogger() *Logger {
+	logger, _ := NewLoggerWithOptions(WithDisabledRemote(), WithOutput(io.Discard))
+	return logger
+}
+
+// SetOutput sets the destination of the local output, os.Stdout by default.
+// Messages that fail to write to it go to EmergencyOutput. Loggers derived
+// with WithFields share the output.
+func (l *Logger) SetOutput(w io.Writer) {
+	if fw, ok := w.(*fallbackWriter); ok {
+		w = fw.out
+	}
+	l.logger.SetOutput(&fallbackWriter{out: w, fallback: l.emergencyOutput()})
+	l.setLocalFormatter() // colors depend on the output
+}
+
+// AddHook attaches a logrus hook, e.g. for Sentry, to the local output. It
+// fires for every message written locally, so not when DisableLocal is set.
+// The entry's message is the message text, with the additional fields as
+// entry data without their leading underscore, or the GELF JSON with
+// LocalGELF. Loggers derived with WithFields share hooks.
+func (l *Logger) AddHook(hook logrus.Hook) {
+	l.logger.AddHook(hook)
+}
+
+// SetLevel sets the minimum level to log. Messages below it are dropped
+// before any work is done. The default is LevelDebug, so trace messages are
+// suppressed. It is safe to call while logging, e.g. from an admin endpoint,
+// and applies to the loggers derived with WithFields as well, but not to
+// those derived with WithLevel or Clone.
+func (l *Logger) SetLevel(level Level) {
+	l.level.Store(int32(level))
+}
+
+// GetLevel returns the minimum level to log
+func (l *Logger) GetLevel() Level {
+	return Level(l.level.Load())
+}
+
+// Flush blocks until all messages buffered by an async Logger have been
+// sent, or returns ctx's error if it ends first. Messages logged while
+// flushing may be waited for too. It returns immediately for a synchronous
+// Logger.
+func (l *Logger) Flush(ctx context.Context) error {
+	if l.async == nil {
+		return nil
+	}
+	return l.async.flush(ctx)
+}
+
+// WithFields returns a child logger that adds data's non-empty fields to
+// every message; fields set on a call override them. The child shares the
+// parent's configuration at the time of the call and its connection, so
+// closing either closes both.
+func (l *Logger) WithFields(data LogData) *Logger {
+	child := *l
+	child.fields = mergeLogData(l.fields, data)
+	if l.autoTrID && child.fields.TransactionID == "" {
+		child.fields.TransactionID = NewTransactionID()
+	}
+	return &child
+}
+
+// WithLevel returns a child logger with its own level, e.g. LevelDebug to
+// trace one suspicious request path while the rest of the service stays
+// quiet. Like WithFields, the child shares everything else with l; SetLevel
+// on either doesn't affect the other.
+func (l *Logger) WithLevel(level Level) *Logger {
+	child := *l
+	child.level = newLevelVar(level)
+	return &child
+}
+
+// RefreshIP re-resolves the cached local IP now and returns it
+func (l *Logger) RefreshIP() string {
+	return l.ip.update()
+}
+
+// Close sends any buffered messages and releases the connection to
+// Graylog. Call it from shutdown hooks so no logs are lost. It waits for
+// the whole backlog however long Graylog takes; use CloseContext to bound
+// the wait. Calling it again, or on a child sharing the connection, is a
+// no-op.
+func (l *Logger) Close() error {
+	return l.CloseContext(context.Background())
+}
+
+// CloseContext is like Close but gives up waiting for an async Logger's
+// backlog once ctx ends: the sends still pending are cancelled and count as
+// failed, or are spooled if SpoolPath is set, and ctx's error is returned.
+func (l *Logger) CloseContext(ctx context.Context) error {
+	var errs []error
+	if l.dedup != nil {
+		l.dedup.flush()
+	}
+	if l.async != nil {
+		errs = append(errs, l.async.close(ctx))
+	}
+	l.closeReconnect()
+	for _, e := range l.endpoints {
+		e.closeReconnect()
+	}
+	if l.spool != nil {
+		l.spool.close()
+	}
+	errs = append(errs, l.overrides.close()...)
+	errs = append(errs, l.conn.close())
+	for _, e := range l.endpoints {
+		errs = append(errs, e.conn.close())
+	}
+	return errors.Join(errs...)
+}
+
+// Log logs a message and sends it to Graylog. The message is always written
+// locally; the returned error reports a failed Graylog send. An async Logger
+// only queues the message, so send failures are not returned.
+func (l *Logger) Log(level Level, message string, data LogData) error {
+	return l.LogContext(context.Background(), level, message, data)
+}
+
+// LogContext is like Log, but the dial and write to Graylog are bounded by
+// ctx's deadline and abandoned when ctx is cancelled. Fields are taken from
+// ctx by the WithContextFields extractors, and if ctx carries an
+// OpenTelemetry span, its IDs are sent as _trace_id and _span_id. For an
+// async Logger, ctx only bounds the wait for room in a full buffer.
+func (l *Logger) LogContext(ctx context.Context, level Level, message string, data LogData) error {
+	return l.logVia(ctx, "", level, message, data)
+}
+
+// Enabled reports whether messages at level pass the level threshold. Log
+// checks it before doing any work, so a filtered-out call costs a load and
+// a compare; check it first to also skip building expensive arguments.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.GetLevel()
+}
+
+// logVia filters and logs a message, sending it synchronously over protocol
+// unless protocol is empty
+func (l *Logger) logVia(ctx context.Context, protocol string, level Level, message string, data LogData) error {
+	if !l.Enabled(level) || !l.limiter.allow(level) {
+		return nil
+	}
+
+	// A new variable, as taking data's address would move the parameter
+	// to the heap before the check above
+	merged := mergeLogData(l.fields, data)
+	l.addContextFields(ctx, &merged)
+	if l.autoTrID && merged.TransactionID == "" {
+		merged.TransactionID = NewTransactionID()
+	}
+	addTraceContext(ctx, &merged)
+	if l.dedup != nil && l.dedup.suppress(l, level, message, merged) {
+		return nil
+	}
+	return l.log(ctx, protocol, level, message, merged)
+}
+
+// BuildGELF returns the GELF payload Log would send for a message, without
+// sending or writing it. It runs the same enrichment, redaction,
+// truncation and validation, and returns the same errors, also passing
+// them to OnError; level filtering, rate limiting and dedup don't apply.
+func (l *Logger) BuildGELF(level Level, message string, data LogData) ([]byte, error) {
+	data = mergeLogData(l.fields, data)
+	if l.autoTrID && data.TransactionID == "" {
+		data.TransactionID = NewTransactionID()
+	}
+	_, jsonData, err := l.encode(level, message, data)
+	return jsonData, err
+}
+
+// log enriches, encodes and ships a message that passed filtering. A
+// non-empty protocol overrides the Logger's and bypasses the async buffer.
+func (l *Logger) log(ctx context.Context, protocol string, level Level, message string, data LogData) error {
+	doc, jsonData, checkErr := l.encode(level, message, data)
 
 	// Log locally
-	switch level {
-	case "INFO":
-		l.logger.Info(string(jsonData))
-	case "ERROR":
-		l.logger.Error(string(jsonData))
-	case "DEBUG":
-		l.logger.Debug(string(jsonData))
-	default:
-		l.logger.Warn(string(jsonData))
+	if !l.DisableLocal {
+		l.writeLocal(level, doc, jsonData)
+	}
+
+	if l.sink != nil {
+		l.sink.record(jsonData)
+	}
+	if l.DisableRemote {
+		return checkErr
+	}
+	if l.syslog() {
+		jsonData = rfc5424(level, doc)
+	}
+	if err := l.route(ctx, level, jsonData); err != nil {
+		checkErr = errors.Join(checkErr, err)
+	}
+	if protocol != "" {
+		e, err := l.overrides.endpoint(l, protocol)
+		if err != nil {
+			return errors.Join(checkErr, err)
+		}
+		return errors.Join(checkErr, e.sendToGraylog(ctx, jsonData, 1))
+	}
+	if l.async != nil {
+		return errors.Join(checkErr, l.enqueue(ctx, jsonData))
 	}
 
 	// Send to Graylog using the chosen protocol
-	l.sendToGraylog(jsonData)
+	return errors.Join(checkErr, l.sendToGraylog(ctx, jsonData, 1))
 }
 
-// sendToGraylog sends log data to Graylog using the selected protocol
-func (l *Logger) sendToGraylog(logData []byte) {
-	address := fmt.Sprintf("%s:%s", l.GraylogHost, l.GraylogPort)
+// encode enriches a message and encodes it as GELF. The returned error
+// reports problems found on the way; the payload is usable regardless.
+func (l *Logger) encode(level Level, message string, data LogData) (map[string]interface{}, []byte, error) {
+	// Automatically set timestamp, hostname, and IP dynamically
+	now := time.Now()
+	if l.TimeFunc != nil {
+		now = l.TimeFunc()
+	}
+	data.Timestamp = now.UTC().Format(time.RFC3339)
+	data.Level = level.String()
+	data.Message = message
+	var checkErr error
+	if data.AppName == "" {
+		data.AppName = l.AppName
+	}
+	if data.AppName == "" && l.RequireAppName {
+		checkErr = ErrNoAppName
+	}
 
-	if l.Protocol == "udp" {
-		err := sendUDP(address, logData)
-		if err != nil {
-			fmt.Println("Failed to send log via UDP:", err)
-		} else {
-			fmt.Println("Log sent successfully to Graylog via UDP!")
+	// Set dynamic hostname and IP if not already provided
+	if data.Hostname == "" && !l.DisableHostname {
+		data.Hostname = l.hostname
+	}
+
+	if data.IPAddress == "" && !l.DisableIPLookup {
+		data.IPAddress = l.ip.get()
+	}
+
+	if l.ReportCaller {
+		if frame, ok := callerFrame(); ok {
+			data.setExtra("_file", frame.File)
+			data.setExtra("_line", frame.Line)
+			data.setExtra("_func", frame.Function)
 		}
-	} else {
-		err := sendTCP(address, logData)
-		if err != nil {
-			fmt.Println("Failed to send log via TCP:", err)
-		} else {
-			fmt.Println("Log sent successfully to Graylog via TCP!")
+	}
+	if l.Stacktrace && level >= l.StacktraceLevel {
+		data.setExtra("_stacktrace", stacktrace())
+	}
+	l.addProcessMetadata(&data)
+
+	sanitize(&data)
+	l.redact(&data)
+
+	doc := l.buildGELF(now, level, data)
+	truncated := l.limitFields(doc)
+	jsonData, marshalErr := marshalGELF(doc)
+	if marshalErr != nil {
+		// Still deliver the message, flagged, rather than nothing
+		marshalErr = fmt.Errorf("graylog: marshal message: %w", marshalErr)
+		jsonData = fallbackGELF(doc, marshalErr)
+		stringifyUnencodable(doc)
+		if l.OnError != nil {
+			l.OnError(jsonData, marshalErr)
+		}
+	} else if limit := l.maxMessageBytes(); limit > 0 && len(jsonData) > limit {
+		jsonData, _ = truncateGELF(doc, limit)
+	}
+	if len(truncated) > 0 && l.OnError != nil {
+		l.OnError(jsonData, fmt.Errorf("graylog: truncated oversized fields: %s", strings.Join(truncated, ", ")))
+	}
+	if l.Validate {
+		if err := l.validate(doc); err != nil {
+			checkErr = errors.Join(checkErr, err)
+			if l.OnError != nil {
+				l.OnError(jsonData, err)
+			}
 		}
 	}
+	return doc, jsonData, errors.Join(checkErr, marshalErr)
 }
 
-// sendUDP sends log data over UDP
-func sendUDP(address string, data []byte) error {
-	conn, err := net.Dial("udp", address)
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
+// Trace logs a message at LevelTrace, which is suppressed by default
+func (l *Logger) Trace(message string, data LogData) error {
+	return l.Log(LevelTrace, message, data)
+}
 
-	_, err = conn.Write(data)
-	return err
+// Debug logs a message at LevelDebug
+func (l *Logger) Debug(message string, data LogData) error {
+	return l.Log(LevelDebug, message, data)
 }
 
-// sendTCP sends log data over TCP
-func sendTCP(address string, data []byte) error {
-	conn, err := net.Dial("tcp", address)
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
+// Info logs a message at LevelInfo
+func (l *Logger) Info(message string, data LogData) error {
+	return l.Log(LevelInfo, message, data)
+}
 
-	_, err = conn.Write(append(data, '\n')) // GELF messages should end with a newline
-	return err
+// Warn logs a message at LevelWarn
+func (l *Logger) Warn(message string, data LogData) error {
+	return l.Log(LevelWarn, message, data)
 }
 
-// GetLocalIP retrieves the local machine's IP address.
-func GetLocalIP() string {
-	addrs, err := net.InterfaceAddrs()
-	if err != nil {
-		return "Unknown"
+// Error logs a message at LevelError
+func (l *Logger) Error(message string, data LogData) error {
+	return l.Log(LevelError, message, data)
+}
+
+// LogKV logs a message with custom fields given as alternating keys and
+// values, e.g. LogKV(LevelInfo, "paid", "amount", 12.5, "currency", "EUR").
+// Pairs with a non-string key and a trailing key without a value are left
+// out and reported in the returned error; the message is logged anyway.
+func (l *Logger) LogKV(level Level, message string, kv ...interface{}) error {
+	if !l.Enabled(level) {
+		return nil
 	}
-	for _, addr := range addrs {
-		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
-			if ipNet.IP.To4() != nil {
-				return ipNet.IP.String()
-			}
+	fields := make(map[string]interface{}, len(kv)/2)
+	var errs []error
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 == len(kv) {
+			errs = append(errs, fmt.Errorf("graylog: LogKV: key %v has no value", kv[i]))
+			break
 		}
+		key, ok := kv[i].(string)
+		if !ok {
+			errs = append(errs, fmt.Errorf("graylog: LogKV: key %v is a %T, not a string", kv[i], kv[i]))
+			continue
+		}
+		fields[key] = kv[i+1]
+	}
+	return errors.Join(append(errs, l.Log(level, message, LogData{Fields: fields}))...)
+}
+
+// LogTimed logs a message with the time elapsed since start, e.g. a
+// request's start, in a numeric _duration_ms field that Graylog can
+// aggregate
+func (l *Logger) LogTimed(start time.Time, level Level, message string, data LogData) error {
+	if !l.Enabled(level) {
+		return nil
+	}
+	return l.LogDuration(time.Since(start), level, message, data)
+}
+
+// LogDuration logs a message with d in a numeric _duration_ms field, in
+// milliseconds with a fractional part
+func (l *Logger) LogDuration(d time.Duration, level Level, message string, data LogData) error {
+	if !l.Enabled(level) {
+		return nil
 	}
-	return "Unknown"
+	data.setExtra("_duration_ms", float64(d)/float64(time.Millisecond))
+	return l.Log(level, message, data)
+}
+
+// fatalCloseTimeout bounds how long Fatal waits for buffered messages
+const fatalCloseTimeout = 5 * time.Second
+
+// Fatal logs a message at LevelFatal, waits up to fatalCloseTimeout until it
+// and any buffered messages have been sent, closes the Logger and exits the
+// process with ExitCode
+func (l *Logger) Fatal(message string, data LogData) {
+	l.Log(LevelFatal, message, data)
+	ctx, cancel := context.WithTimeout(context.Background(), fatalCloseTimeout)
+	l.CloseContext(ctx)
+	cancel()
+	os.Exit(l.ExitCode)
 }
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloseContextNonAcceptingListener(t *testing.T) {
+	// The listener never accepts, so once the socket buffers fill every
+	// write blocks until WriteTimeout
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	l, err := NewLoggerWithOptions(
+		WithGraylog(host, port, "tcp"),
+		WithAsync(32, OverflowBlock),
+		WithKeepAlive(0, time.Minute),
+		WithMaxMessageBytes(-1),
+		WithDisabledIPLookup(),
+		WithDisabledLocal(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const messages = 16
+	big := strings.Repeat("x", 2<<20)
+	for range messages {
+		if err := l.Info(big, LogData{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := l.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Flush = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.CloseContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CloseContext = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("CloseContext took %v", elapsed)
+	}
+
+	stats := l.Stats()
+	if stats.Sent+stats.Failed != messages || stats.Failed == 0 {
+		t.Errorf("Sent = %d, Failed = %d, want %d in total with some failed", stats.Sent, stats.Failed, messages)
+	}
+}
+
+func TestLogFilteredAllocs(t *testing.T) {
+	l, sink := NewTestLogger(WithLevel(LevelInfo))
+	data := LogData{AppName: "bench", Fields: map[string]interface{}{"user": "alice"}}
+	start := time.Now()
+
+	tests := []struct {
+		name string
+		log  func()
+	}{
+		{"Debug", func() { l.Debug("filtered", data) }},
+		{"Log", func() { l.Log(LevelTrace, "filtered", data) }},
+		{"LogKV", func() { l.LogKV(LevelDebug, "filtered", "user", "alice") }},
+		{"LogTimed", func() { l.LogTimed(start, LevelDebug, "filtered", data) }},
+		{"LogDuration", func() { l.LogDuration(time.Second, LevelDebug, "filtered", data) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(100, tt.log); allocs != 0 {
+				t.Errorf("%v allocs per filtered call, want 0", allocs)
+			}
+		})
+	}
+	if n := len(sink.Payloads()); n != 0 {
+		t.Errorf("%d messages recorded, want 0", n)
+	}
+}
+
+func BenchmarkLogFiltered(b *testing.B) {
+	l, _ := NewTestLogger(WithLevel(LevelInfo))
+	data := LogData{AppName: "bench", Fields: map[string]interface{}{"user": "alice"}}
+	b.ReportAllocs()
+	for range b.N {
+		l.Debug("filtered", data)
+	}
+}
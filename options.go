@@ -0,0 +1,458 @@
+package logger
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// Option configures a Logger built by NewLoggerWithOptions
+type Option func(*Logger)
+
+// WithGraylog sets the Graylog endpoint and protocol ("udp", "tcp", "http",
+// "https", "unix" or "unixgram"). For the Unix socket protocols host is the
+// socket path and port is unused.
+func WithGraylog(host, port, protocol string) Option {
+	return func(l *Logger) {
+		l.GraylogHost = host
+		l.GraylogPort = port
+		l.Protocol = protocol
+	}
+}
+
+// WithDestination adds an extra Graylog endpoint that receives every
+// message; see Destination
+func WithDestination(host, port, protocol string) Option {
+	return func(l *Logger) {
+		l.Destinations = append(l.Destinations, Destination{Host: host, Port: port, Protocol: protocol})
+	}
+}
+
+// WithLevelDestination adds a Graylog endpoint that only receives messages
+// at minLevel or above; see Destination.MinLevel
+func WithLevelDestination(minLevel Level, host, port, protocol string) Option {
+	return func(l *Logger) {
+		l.Destinations = append(l.Destinations, Destination{Host: host, Port: port, Protocol: protocol, MinLevel: minLevel})
+	}
+}
+
+// WithLevelOutput also writes messages at minLevel or above locally to w,
+// e.g. an error file, in the LocalFormat. Like hooks, it only applies when
+// the local output is enabled.
+func WithLevelOutput(minLevel Level, w io.Writer) Option {
+	return func(l *Logger) {
+		l.AddHook(&levelOutputHook{minLevel: minLevel, out: w})
+	}
+}
+
+// WithSpool keeps messages that could not be sent in the file at path, up
+// to maxBytes (zero for no limit), and replays them once Graylog recovers
+func WithSpool(path string, maxBytes int64) Option {
+	return func(l *Logger) {
+		l.SpoolPath = path
+		l.SpoolMaxBytes = maxBytes
+	}
+}
+
+// WithDryRun passes what would be sent to Graylog to fn instead of sending
+// it; see DryRun
+func WithDryRun(fn func(protocol, address string, frames [][]byte)) Option {
+	return func(l *Logger) {
+		l.DryRun = true
+		l.OnDryRun = fn
+	}
+}
+
+// WithDisabledRemote turns off Graylog sends; messages are only written
+// locally and no Graylog address is required
+func WithDisabledRemote() Option {
+	return func(l *Logger) {
+		l.DisableRemote = true
+	}
+}
+
+// WithDisabledHostname stops the detected hostname from being added to
+// messages; see DisableHostname
+func WithDisabledHostname() Option {
+	return func(l *Logger) {
+		l.DisableHostname = true
+	}
+}
+
+// WithDisabledIPLookup stops the local IP from being looked up and added
+// to messages; see DisableIPLookup
+func WithDisabledIPLookup() Option {
+	return func(l *Logger) {
+		l.DisableIPLookup = true
+	}
+}
+
+// WithResolveHost makes the constructor fail if the Graylog host doesn't
+// resolve, to catch DNS mistakes at startup
+func WithResolveHost() Option {
+	return func(l *Logger) {
+		l.resolveHost = true
+	}
+}
+
+// WithOutput sets the destination of the local log output
+func WithOutput(w io.Writer) Option {
+	return func(l *Logger) {
+		l.SetOutput(w)
+	}
+}
+
+// WithEmergencyOutput sets where messages go when the local output fails;
+// see EmergencyOutput
+func WithEmergencyOutput(w io.Writer) Option {
+	return func(l *Logger) {
+		l.EmergencyOutput = w
+	}
+}
+
+// WithTransactionIDs fills in a random TransactionID for messages without
+// one. A Logger derived with WithFields gets a single ID for all its
+// messages, available from its TransactionID method.
+func WithTransactionIDs() Option {
+	return func(l *Logger) {
+		l.autoTrID = true
+	}
+}
+
+// WithContextFields registers extractors that LogContext uses to fill in
+// fields from its context, so correlation values stored there by
+// middleware don't have to be copied at every call
+func WithContextFields(extractors ...ContextExtractor) Option {
+	return func(l *Logger) {
+		l.extractors = append(l.extractors, extractors...)
+	}
+}
+
+// WithDefaults adds data's non-empty fields to every message, e.g. the
+// Channel or BankCode of a service instance. WithFields and fields set on
+// a call override them.
+func WithDefaults(data LogData) Option {
+	return func(l *Logger) {
+		l.fields = mergeLogData(l.fields, data)
+	}
+}
+
+// WithConsoleFormat makes the local output human-readable for development:
+// logrus text with the message and fields, colored when writing to a
+// terminal. Graylog still receives GELF JSON.
+func WithConsoleFormat() Option {
+	return func(l *Logger) {
+		l.LocalFormat = LocalText
+	}
+}
+
+// WithLocalFormat sets how messages are written locally
+func WithLocalFormat(format LocalFormat) Option {
+	return func(l *Logger) {
+		l.LocalFormat = format
+	}
+}
+
+// WithDisabledLocal turns off the local output; messages are only sent to
+// Graylog
+func WithDisabledLocal() Option {
+	return func(l *Logger) {
+		l.DisableLocal = true
+	}
+}
+
+// WithLevel sets the minimum level to log, see Logger.SetLevel
+func WithLevel(level Level) Option {
+	return func(l *Logger) {
+		l.SetLevel(level)
+	}
+}
+
+// WithAppName sets the app name used when LogData.AppName is empty
+func WithAppName(name string) Option {
+	return func(l *Logger) {
+		l.AppName = name
+	}
+}
+
+// WithMaxMessageBytes sets MaxMessageBytes, the encoded size above which
+// messages are truncated
+func WithMaxMessageBytes(n int) Option {
+	return func(l *Logger) {
+		l.MaxMessageBytes = n
+	}
+}
+
+// WithFieldLimits caps the size of string additional fields: every field
+// at maxBytes, and the fields in limits at their own size; see
+// MaxFieldBytes
+func WithFieldLimits(maxBytes int, limits map[string]int) Option {
+	return func(l *Logger) {
+		l.MaxFieldBytes = maxBytes
+		l.FieldLimits = limits
+	}
+}
+
+// WithFieldNameMap renames additional fields; see FieldNameMap
+func WithFieldNameMap(names map[string]string) Option {
+	return func(l *Logger) {
+		l.FieldNameMap = names
+	}
+}
+
+// WithValidation checks messages before they are sent; see Validate
+func WithValidation() Option {
+	return func(l *Logger) {
+		l.Validate = true
+	}
+}
+
+// WithRequiredAppName makes Log return ErrNoAppName for messages without an
+// app name; see RequireAppName
+func WithRequiredAppName() Option {
+	return func(l *Logger) {
+		l.RequireAppName = true
+	}
+}
+
+// WithCompression sets the UDP and HTTP payload compression: "none", "gzip"
+// or "zlib"
+func WithCompression(algorithm string) Option {
+	return func(l *Logger) {
+		l.Compression = algorithm
+	}
+}
+
+// WithCompressionThreshold sends payloads smaller than size bytes
+// uncompressed; see CompressionThreshold
+func WithCompressionThreshold(size int) Option {
+	return func(l *Logger) {
+		l.CompressionThreshold = size
+	}
+}
+
+// WithFormat sets what messages are sent as, FormatGELF or FormatRFC5424;
+// see Format
+func WithFormat(format string) Option {
+	return func(l *Logger) {
+		l.Format = format
+	}
+}
+
+// WithTimeout bounds the time each send to Graylog may take, dialing,
+// writing and retrying included, so a black-holed endpoint can't hold up a
+// caller for longer
+func WithTimeout(d time.Duration) Option {
+	return func(l *Logger) {
+		l.Timeout = d
+	}
+}
+
+// WithAsync sends messages from a background goroutine. Log queues up to
+// bufferSize messages and policy decides what happens when the queue is
+// full; dropped messages are counted in Stats and passed to OnError with
+// ErrBufferFull. Call Flush or Close before exiting so queued messages
+// aren't lost. bufferSize must be at least 1.
+func WithAsync(bufferSize int, policy OverflowPolicy) Option {
+	return func(l *Logger) {
+		l.useAsync = true
+		l.asyncSize = bufferSize
+		l.asyncPolicy = policy
+	}
+}
+
+// WithBlockTimeout bounds how long Log waits for room in a full async
+// buffer with OverflowBlock; see BlockTimeout
+func WithBlockTimeout(d time.Duration) Option {
+	return func(l *Logger) {
+		l.BlockTimeout = d
+	}
+}
+
+// WithKeepAlive sets the TCP keep-alive interval and the per-write
+// timeout; see KeepAlive and WriteTimeout
+func WithKeepAlive(interval, writeTimeout time.Duration) Option {
+	return func(l *Logger) {
+		l.KeepAlive = interval
+		l.WriteTimeout = writeTimeout
+	}
+}
+
+// WithDialFunc opens connections to Graylog with dial, e.g. through a
+// proxy; see DialFunc
+func WithDialFunc(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(l *Logger) {
+		l.DialFunc = dial
+	}
+}
+
+// WithIPRefresh re-resolves the cached local IP every interval, for
+// long-running processes whose address may change
+func WithIPRefresh(interval time.Duration) Option {
+	return func(l *Logger) {
+		l.ip.refresh = interval
+	}
+}
+
+// WithRateLimit caps messages at level to perSecond, allowing bursts of up
+// to burst. Excess messages are dropped before any work is done and counted
+// by DroppedByRateLimit. Levels without a limit are never dropped, so e.g.
+// DEBUG can be sampled while ERROR always gets through.
+func WithRateLimit(level Level, perSecond float64, burst int) Option {
+	return func(l *Logger) {
+		l.limiter.setLimit(level, perSecond, burst)
+	}
+}
+
+// WithDedup collapses identical consecutive messages: within window, repeats
+// of a message are suppressed and reported by one "(repeated N times)"
+// summary with a _repeated count. Messages differing only in TransactionID
+// count as identical.
+func WithDedup(window time.Duration) Option {
+	return func(l *Logger) {
+		l.dedup = &deduper{window: window}
+	}
+}
+
+// WithRetry retries failed sends up to maxRetries times with exponential
+// backoff starting at backoff
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(l *Logger) {
+		l.MaxRetries = maxRetries
+		l.RetryBackoff = backoff
+	}
+}
+
+// WithHTTPClient sets the client used by the "http" and "https" protocols
+func WithHTTPClient(client *http.Client) Option {
+	return func(l *Logger) {
+		l.HTTPClient = client
+	}
+}
+
+// WithReconnect buffers up to bufferSize messages while a connection is
+// down and reconnects in the background; see ReconnectBuffer
+func WithReconnect(bufferSize int, overflow OverflowPolicy) Option {
+	return func(l *Logger) {
+		l.ReconnectBuffer = bufferSize
+		l.ReconnectOverflow = overflow
+	}
+}
+
+// WithCircuitBreaker fails sends at once for cooldown after threshold
+// consecutive failures; see BreakerThreshold
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(l *Logger) {
+		l.BreakerThreshold = threshold
+		l.BreakerCooldown = cooldown
+	}
+}
+
+// WithExitCode sets the process exit code used by Fatal
+func WithExitCode(code int) Option {
+	return func(l *Logger) {
+		l.ExitCode = code
+	}
+}
+
+// WithRedactFields masks the named fields, e.g. "param_a" or a custom field
+// key, in every message
+func WithRedactFields(names ...string) Option {
+	return func(l *Logger) {
+		l.RedactFields = append(l.RedactFields, names...)
+	}
+}
+
+// WithRedactPatterns masks matches of patterns, e.g. PANPattern, in every
+// string value
+func WithRedactPatterns(patterns ...*regexp.Regexp) Option {
+	return func(l *Logger) {
+		l.RedactPatterns = append(l.RedactPatterns, patterns...)
+	}
+}
+
+// WithOnError registers a callback for failed Graylog sends, see
+// Logger.OnError
+func WithOnError(fn func(payload []byte, err error)) Option {
+	return func(l *Logger) {
+		l.OnError = fn
+	}
+}
+
+// WithNullDelimiter frames stream messages with a null byte; see
+// NullDelimiter
+func WithNullDelimiter() Option {
+	return func(l *Logger) {
+		l.NullDelimiter = true
+	}
+}
+
+// WithBatching makes an async TCP Logger send up to batchSize messages per
+// write, waiting at most flushInterval to fill a batch. Only takes effect
+// together with WithAsync.
+func WithBatching(batchSize int, flushInterval time.Duration) Option {
+	return func(l *Logger) {
+		l.BatchSize = batchSize
+		l.FlushInterval = flushInterval
+	}
+}
+
+// WithTimeFunc sets the clock used to stamp messages
+func WithTimeFunc(fn func() time.Time) Option {
+	return func(l *Logger) {
+		l.TimeFunc = fn
+	}
+}
+
+// WithTimestampFormat sets the GELF timestamp format: TimestampEpoch or a
+// time layout such as time.RFC3339
+func WithTimestampFormat(format string) Option {
+	return func(l *Logger) {
+		l.TimestampFormat = format
+	}
+}
+
+// WithCaller records where each message was logged from, see
+// Logger.ReportCaller
+func WithCaller() Option {
+	return func(l *Logger) {
+		l.ReportCaller = true
+	}
+}
+
+// WithProcessMetadata adds the process ID, Go version, goroutine count and,
+// if not empty, appVersion to every message
+func WithProcessMetadata(appVersion string) Option {
+	return func(l *Logger) {
+		l.ProcessMetadata = true
+		l.AppVersion = appVersion
+	}
+}
+
+// WithStacktrace attaches a stack trace to messages at minLevel or above,
+// e.g. LevelError
+func WithStacktrace(minLevel Level) Option {
+	return func(l *Logger) {
+		l.Stacktrace = true
+		l.StacktraceLevel = minLevel
+	}
+}
+
+// WithTLS enables TLS for the TCP transport, see NewTLSConfig
+func WithTLS(cfg *tls.Config) Option {
+	return func(l *Logger) {
+		l.TLSConfig = cfg
+	}
+}
+
+// WithInsecureSkipVerify enables TLS for the TCP transport without verifying
+// Graylog's certificate. This is DANGEROUS and only meant for development.
+func WithInsecureSkipVerify() Option {
+	return func(l *Logger) {
+		l.TLSInsecureSkipVerify = true
+	}
+}
@@ -0,0 +1,18 @@
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// addTraceContext adds the IDs of the OpenTelemetry span in ctx, if any, as
+// _trace_id and _span_id so Graylog messages link to their trace
+func addTraceContext(ctx context.Context, data *LogData) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	data.setExtra("_trace_id", sc.TraceID().String())
+	data.setExtra("_span_id", sc.SpanID().String())
+}
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Ping checks that Graylog and every Destination are reachable, using the
+// same TLS and Timeout settings as sends: TCP endpoints are dialed, HTTP
+// ones get a HEAD request. UDP has no handshake, so for UDP Ping only checks
+// that the address resolves and can be dialed. A Logger with DisableRemote
+// has nothing to check, and one with DryRun must not touch the network, so
+// both return nil.
+func (l *Logger) Ping(ctx context.Context) error {
+	if l.DisableRemote || l.DryRun {
+		return nil
+	}
+	errs := []error{l.ping(ctx)}
+	for _, e := range l.endpoints {
+		errs = append(errs, e.ping(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// ping checks this Logger's own endpoint
+func (l *Logger) ping(ctx context.Context) error {
+	var err error
+	if isHTTP(l.Protocol) {
+		err = l.pingHTTP(ctx)
+	} else if conn, dialErr := l.dial(ctx); dialErr != nil {
+		err = dialErr
+	} else {
+		err = conn.Close()
+	}
+
+	if err != nil {
+		return fmt.Errorf("graylog: ping via %s to %s: %w", l.Protocol, l.address(), err)
+	}
+	return nil
+}
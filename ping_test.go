@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPingDryRun(t *testing.T) {
+	tests := []struct {
+		protocol string
+		host     string
+	}{
+		{"udp", "graylog.invalid"},
+		{"tcp", "graylog.invalid"},
+		{"http", "graylog.invalid"},
+		{"https", "graylog.invalid"},
+		{"unix", "/nonexistent/graylog.sock"},
+		{"unixgram", "/nonexistent/graylog.sock"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			l, err := NewLoggerWithOptions(
+				WithGraylog(tt.host, "12201", tt.protocol),
+				WithDestination(tt.host, "12202", tt.protocol),
+				WithDryRun(nil),
+				WithDisabledIPLookup(),
+				WithDisabledLocal(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Ping(context.Background()); err != nil {
+				t.Errorf("Ping = %v, want nil under DryRun", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package logger
+
+import (
+	"os"
+	"runtime"
+)
+
+// pid and goVersion don't change while the process runs
+var (
+	pid       = os.Getpid()
+	goVersion = runtime.Version()
+)
+
+// addProcessMetadata adds the fields enabled by ProcessMetadata and
+// AppVersion
+func (l *Logger) addProcessMetadata(data *LogData) {
+	if l.ProcessMetadata {
+		data.setExtra("_pid", pid)
+		data.setExtra("_go_version", goVersion)
+		data.setExtra("_num_goroutine", runtime.NumGoroutine())
+	}
+	if l.AppVersion != "" {
+		data.setExtra("_app_version", l.AppVersion)
+	}
+}
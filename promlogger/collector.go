@@ -0,0 +1,76 @@
+// Package promlogger exposes a logger.Logger's delivery metrics to
+// Prometheus. It lives in its own package so the core logger doesn't depend
+// on the Prometheus client.
+package promlogger
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sahuprasantakumar975/logger"
+)
+
+const namespace = "graylog_logger"
+
+// Collector is a prometheus.Collector for a Logger's counters and send
+// latency
+type Collector struct {
+	logger *logger.Logger
+
+	sent        *prometheus.Desc
+	failed      *prometheus.Desc
+	dropped     *prometheus.Desc
+	bytesSent   *prometheus.Desc
+	bufferDepth *prometheus.Desc
+	latency     prometheus.Histogram
+}
+
+// NewCollector returns a Collector for l, ready to be registered:
+//
+//	prometheus.MustRegister(promlogger.NewCollector(l))
+func NewCollector(l *logger.Logger) *Collector {
+	c := &Collector{
+		logger: l,
+		sent: prometheus.NewDesc(namespace+"_messages_sent_total",
+			"Messages delivered to Graylog.", nil, nil),
+		failed: prometheus.NewDesc(namespace+"_messages_failed_total",
+			"Messages whose send to Graylog failed after retries.", nil, nil),
+		dropped: prometheus.NewDesc(namespace+"_messages_dropped_total",
+			"Messages dropped by a full async buffer or rate limiting.", nil, nil),
+		bytesSent: prometheus.NewDesc(namespace+"_bytes_sent_total",
+			"Bytes written to Graylog.", nil, nil),
+		bufferDepth: prometheus.NewDesc(namespace+"_buffer_depth",
+			"Messages waiting in the async buffer.", nil, nil),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "send_duration_seconds",
+			Help:      "Duration of sends to Graylog, retries included.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+	l.AddSendObserver(func(d time.Duration, _ int, _ error) {
+		c.latency.Observe(d.Seconds())
+	})
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sent
+	ch <- c.failed
+	ch <- c.dropped
+	ch <- c.bytesSent
+	ch <- c.bufferDepth
+	c.latency.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.logger.Stats()
+	ch <- prometheus.MustNewConstMetric(c.sent, prometheus.CounterValue, float64(stats.Sent))
+	ch <- prometheus.MustNewConstMetric(c.failed, prometheus.CounterValue, float64(stats.Failed))
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(stats.Dropped))
+	ch <- prometheus.MustNewConstMetric(c.bytesSent, prometheus.CounterValue, float64(stats.BytesSent))
+	ch <- prometheus.MustNewConstMetric(c.bufferDepth, prometheus.GaugeValue, float64(stats.BufferDepth))
+	c.latency.Collect(ch)
+}
@@ -0,0 +1,108 @@
+package promlogger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sahuprasantakumar975/logger"
+)
+
+func TestCollector(t *testing.T) {
+	// While down, dialing fails and the open connection is closed
+	var down atomic.Bool
+	var server atomic.Pointer[net.Conn]
+	l, err := logger.NewLoggerWithOptions(
+		logger.WithGraylog("graylog", "12201", "tcp"),
+		logger.WithDialFunc(func(context.Context, string, string) (net.Conn, error) {
+			if down.Load() {
+				return nil, errors.New("connection refused")
+			}
+			client, conn := net.Pipe()
+			server.Store(&conn)
+			go io.Copy(io.Discard, conn)
+			return client, nil
+		}),
+		logger.WithDisabledIPLookup(),
+		logger.WithDisabledLocal(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	c := NewCollector(l)
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+
+	tests := []struct {
+		name         string
+		down         bool
+		messages     int
+		wantSent     int
+		wantFailed   int
+		wantObserved uint64
+	}{
+		{"nothing logged", false, 0, 0, 0, 0},
+		{"sent", false, 2, 2, 0, 2},
+		{"failed", true, 1, 2, 1, 3},
+		{"sent again", false, 1, 3, 1, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down.Store(tt.down)
+			if conn := server.Load(); tt.down && conn != nil {
+				(*conn).Close()
+			}
+			for range tt.messages {
+				l.Info("order placed", logger.LogData{})
+			}
+
+			want := fmt.Sprintf(`
+# HELP graylog_logger_messages_sent_total Messages delivered to Graylog.
+# TYPE graylog_logger_messages_sent_total counter
+graylog_logger_messages_sent_total %d
+# HELP graylog_logger_messages_failed_total Messages whose send to Graylog failed after retries.
+# TYPE graylog_logger_messages_failed_total counter
+graylog_logger_messages_failed_total %d
+# HELP graylog_logger_messages_dropped_total Messages dropped by a full async buffer or rate limiting.
+# TYPE graylog_logger_messages_dropped_total counter
+graylog_logger_messages_dropped_total 0
+# HELP graylog_logger_bytes_sent_total Bytes written to Graylog.
+# TYPE graylog_logger_bytes_sent_total counter
+graylog_logger_bytes_sent_total %d
+`, tt.wantSent, tt.wantFailed, l.Stats().BytesSent)
+			if err := testutil.GatherAndCompare(reg, strings.NewReader(want),
+				"graylog_logger_messages_sent_total",
+				"graylog_logger_messages_failed_total",
+				"graylog_logger_messages_dropped_total",
+				"graylog_logger_bytes_sent_total",
+			); err != nil {
+				t.Error(err)
+			}
+			if tt.wantSent > 0 && l.Stats().BytesSent == 0 {
+				t.Error("no bytes counted for the messages sent")
+			}
+
+			families, err := reg.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var observed uint64
+			for _, family := range families {
+				if family.GetName() == "graylog_logger_send_duration_seconds" {
+					observed = family.GetMetric()[0].GetHistogram().GetSampleCount()
+				}
+			}
+			if observed != tt.wantObserved {
+				t.Errorf("%d sends observed, want %d", observed, tt.wantObserved)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tokenBucket allows rate messages per second with bursts of up to burst
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter caps the message rate per level. Levels without a bucket are
+// never limited.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[Level]*tokenBucket
+	dropped atomic.Uint64
+}
+
+// setLimit limits level to perSecond messages with bursts of burst
+func (r *rateLimiter) setLimit(level Level, perSecond float64, burst int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.buckets == nil {
+		r.buckets = map[Level]*tokenBucket{}
+	}
+	r.buckets[level] = &tokenBucket{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// allow reports whether a message at level may be logged, counting it as
+// dropped if not
+func (r *rateLimiter) allow(level Level) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.buckets[level]
+	if !ok {
+		return true
+	}
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		r.dropped.Add(1)
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// DroppedByRateLimit returns how many messages rate limiting has dropped
+func (l *Logger) DroppedByRateLimit() uint64 {
+	return l.limiter.dropped.Load()
+}
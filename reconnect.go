@@ -0,0 +1,205 @@
+package logger
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// reconnector buffers messages for a stream or datagram endpoint while its
+// connection is down and reconnects in the background, with backoff, then
+// sends the buffer in order
+type reconnector struct {
+	size   int
+	policy OverflowPolicy
+
+	mu      sync.Mutex
+	active  bool // reconnecting; new messages are buffered
+	closed  bool
+	pending []bufferedPayload
+	stop    chan struct{}
+	done    chan struct{} // closed when the reconnect loop exits
+}
+
+// bufferedPayload is uncompressed log data waiting for a reconnect
+type bufferedPayload struct {
+	data     []byte
+	messages int
+}
+
+// initReconnect gives l its own reconnector if ReconnectBuffer is set and
+// l's protocol uses a connection
+func (l *Logger) initReconnect() {
+	l.reconnect = nil
+	if l.ReconnectBuffer > 0 && !isHTTP(l.Protocol) {
+		l.reconnect = &reconnector{size: l.ReconnectBuffer, policy: l.ReconnectOverflow, stop: make(chan struct{})}
+	}
+}
+
+// buffer adds data to l's buffer if a reconnect is under way, reporting
+// whether it did. With start, a reconnect loop is started if none is.
+func (l *Logger) buffer(data []byte, messages int, start bool) bool {
+	r := l.reconnect
+	r.mu.Lock()
+	if r.closed || (!r.active && !start) {
+		r.mu.Unlock()
+		return false
+	}
+
+	p := bufferedPayload{data, messages}
+	var dropped *bufferedPayload
+	if len(r.pending) >= r.size {
+		if r.policy == OverflowDropOldest {
+			oldest := r.pending[0]
+			dropped = &oldest
+			r.pending = r.pending[1:]
+		} else {
+			dropped = &p
+		}
+	}
+	if dropped != &p {
+		r.pending = append(r.pending, p)
+	}
+	if !r.active {
+		r.active = true
+		r.done = make(chan struct{})
+		go l.reconnectLoop(r.stop, r.done)
+	}
+	r.mu.Unlock()
+
+	// Outside the lock, as OnError may log
+	if dropped != nil {
+		l.dropBuffered(*dropped)
+	}
+	return true
+}
+
+// next returns the oldest buffered payload, ending the reconnect when the
+// buffer is empty
+func (r *reconnector) next() (bufferedPayload, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) == 0 {
+		r.active = false
+		return bufferedPayload{}, false
+	}
+	p := r.pending[0]
+	r.pending = r.pending[1:]
+	return p, true
+}
+
+// requeue puts a payload that failed to send back at the front
+func (r *reconnector) requeue(p bufferedPayload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = append([]bufferedPayload{p}, r.pending...)
+}
+
+// close stops the reconnect loop and returns the payloads still buffered
+func (r *reconnector) close() []bufferedPayload {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.stop)
+	}
+	done := r.done
+	r.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pending := r.pending
+	r.pending = nil
+	return pending
+}
+
+// reconnectLoop redials with backoff until the connection is back, then
+// sends the buffer. It stops when the buffer is empty or on close.
+func (l *Logger) reconnectLoop(stop <-chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	dial := func() (net.Conn, error) {
+		return l.dial(ctx)
+	}
+	for attempt := 0; ; attempt++ {
+		if !sleep(ctx, l.retryDelay(attempt)) {
+			return
+		}
+		if err := l.conn.dial(dial); err != nil {
+			continue
+		}
+		if !l.sendBuffered(ctx, dial) {
+			continue // the connection dropped again
+		}
+		return
+	}
+}
+
+// sendBuffered sends the buffered payloads, returning false if a send fails
+func (l *Logger) sendBuffered(ctx context.Context, dial func() (net.Conn, error)) bool {
+	for {
+		p, ok := l.reconnect.next()
+		if !ok {
+			return true
+		}
+
+		payload := p.data
+		if isDatagram(l.Protocol) {
+			var err error
+			if payload, err = compress(l.compression(len(p.data)), p.data); err != nil {
+				l.counters.failed.Add(uint64(p.messages))
+				continue
+			}
+		}
+		err := l.conn.write(dial, func(conn net.Conn) error {
+			return l.send(ctx, conn, payload)
+		})
+		if err != nil {
+			l.reconnect.requeue(p)
+			return false
+		}
+		l.counters.sent.Add(uint64(p.messages))
+		l.counters.bytes.Add(uint64(len(payload)))
+	}
+}
+
+// dropBuffered counts and reports a payload dropped by a full async or
+// reconnect buffer
+func (l *Logger) dropBuffered(p bufferedPayload) {
+	l.counters.dropped.Add(uint64(p.messages))
+	l.counters.overflowed.Add(uint64(p.messages))
+	if l.OnError != nil {
+		l.OnError(p.data, ErrBufferFull)
+	}
+}
+
+// closeReconnect stops reconnecting. Messages still buffered are spooled if
+// a spool is set, otherwise they count as failed.
+func (l *Logger) closeReconnect() {
+	if l.reconnect == nil {
+		return
+	}
+	for _, p := range l.reconnect.close() {
+		if l.spool != nil {
+			l.spoolPayload(p.data, p.messages)
+			continue
+		}
+		l.counters.failed.Add(uint64(p.messages))
+		if l.OnError != nil {
+			l.OnError(p.data, ErrClosed)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReconnectBuffer(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      OverflowPolicy
+		wantSent    []string
+		wantDropped []string
+	}{
+		{"drop oldest", OverflowDropOldest, []string{"m3", "m4", "m5"}, []string{"m1", "m2"}},
+		{"drop newest", OverflowDrop, []string{"m1", "m2", "m3"}, []string{"m4", "m5"}},
+		{"block drops newest", OverflowBlock, []string{"m1", "m2", "m3"}, []string{"m4", "m5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graylog := &testGraylog{}
+			graylog.down.Store(true)
+
+			var mu sync.Mutex
+			var dropped []string
+			l, err := NewLoggerWithOptions(
+				WithGraylog("graylog", "12201", "tcp"),
+				WithDialFunc(graylog.dial),
+				WithReconnect(3, tt.policy),
+				WithRetry(0, 10*time.Millisecond),
+				WithOnError(func(payload []byte, err error) {
+					if errors.Is(err, ErrBufferFull) {
+						mu.Lock()
+						dropped = append(dropped, shortMessage(payload))
+						mu.Unlock()
+					}
+				}),
+				WithDisabledIPLookup(),
+				WithDisabledLocal(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 1; i <= 5; i++ {
+				if err := l.Info(fmt.Sprintf("m%d", i), LogData{}); err != nil {
+					t.Fatalf("Info while down = %v, want it buffered", err)
+				}
+			}
+			graylog.down.Store(false)
+
+			if got := graylog.wait(t, len(tt.wantSent)); !slices.Equal(got, tt.wantSent) {
+				t.Errorf("sent %q, want %q", got, tt.wantSent)
+			}
+			// Close waits for the reconnect loop, so the counters are final
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(dropped, tt.wantDropped) {
+				t.Errorf("dropped %q, want %q", dropped, tt.wantDropped)
+			}
+			stats := l.Stats()
+			if stats.Dropped != uint64(len(tt.wantDropped)) || stats.Sent != uint64(len(tt.wantSent)) {
+				t.Errorf("Stats Dropped = %d, Sent = %d, want %d and %d", stats.Dropped, stats.Sent, len(tt.wantDropped), len(tt.wantSent))
+			}
+		})
+	}
+}
+
+func TestReconnectBufferOnClose(t *testing.T) {
+	graylog := &testGraylog{}
+	graylog.down.Store(true)
+
+	var closed int
+	l, err := NewLoggerWithOptions(
+		WithGraylog("graylog", "12201", "tcp"),
+		WithDialFunc(graylog.dial),
+		WithReconnect(10, OverflowDropOldest),
+		WithRetry(0, time.Hour),
+		WithOnError(func(_ []byte, err error) {
+			if errors.Is(err, ErrClosed) {
+				closed++
+			}
+		}),
+		WithDisabledIPLookup(),
+		WithDisabledLocal(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		l.Info("pending", LogData{})
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := l.Stats(); stats.Failed != 3 || closed != 3 {
+		t.Errorf("Failed = %d with %d ErrClosed reports, want 3 and 3", stats.Failed, closed)
+	}
+}
@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+)
+
+// redactedValue replaces masked values
+const redactedValue = "****"
+
+// Common patterns for RedactPatterns
+var (
+	// PANPattern matches card numbers of 13 to 19 digits, optionally
+	// separated by spaces or dashes
+	PANPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	// EmailPattern matches email addresses
+	EmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// redact masks the fields named in RedactFields and replaces matches of
+// RedactPatterns in all other string values, custom fields and those the
+// logger adds, such as _error_cause, included. Field names are matched
+// case-insensitively, ignoring a leading underscore, against LogData's JSON
+// names and custom field keys.
+func (l *Logger) redact(data *LogData) {
+	if len(l.RedactFields) == 0 && len(l.RedactPatterns) == 0 {
+		return
+	}
+
+	masked := make(map[string]bool, len(l.RedactFields))
+	for _, name := range l.RedactFields {
+		masked[strings.TrimPrefix(strings.ToLower(name), "_")] = true
+	}
+
+	for key, value := range data.stringFields() {
+		if *value == "" {
+			continue
+		}
+		if masked[key] {
+			*value = redactedValue
+		} else {
+			*value = l.redactString(*value)
+		}
+	}
+
+	data.Fields = l.redactFields(data.Fields, masked)
+	data.extra = l.redactFields(data.extra, masked)
+}
+
+// redactFields returns a copy of fields, so the caller's map is not
+// modified, with the masked names and pattern matches redacted
+func (l *Logger) redactFields(fields map[string]interface{}, masked map[string]bool) map[string]interface{} {
+	if len(fields) == 0 {
+		return fields
+	}
+	redacted := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if masked[strings.TrimPrefix(strings.ToLower(key), "_")] {
+			value = redactedValue
+		} else if s, ok := value.(string); ok {
+			value = l.redactString(s)
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// redactString replaces every match of RedactPatterns in s
+func (l *Logger) redactString(s string) string {
+	for _, pattern := range l.RedactPatterns {
+		s = pattern.ReplaceAllString(s, redactedValue)
+	}
+	return s
+}
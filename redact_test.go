@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"maps"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestRedactPatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern *regexp.Regexp
+		in      string
+		want    string
+	}{
+		{"pan", PANPattern, "card 4111111111111111 declined", "card **** declined"},
+		{"pan with spaces", PANPattern, "card 4111 1111 1111 1111", "card ****"},
+		{"pan with dashes", PANPattern, "4111-1111-1111-1111 ok", "**** ok"},
+		{"pan of 13 digits", PANPattern, "pan 4222222222222", "pan ****"},
+		{"pan of 19 digits", PANPattern, "pan 6011000000000000004", "pan ****"},
+		{"too short for a pan", PANPattern, "rrn 123456789012", "rrn 123456789012"},
+		{"too long for a pan", PANPattern, "id 12345678901234567890", "id 12345678901234567890"},
+		{"email", EmailPattern, "sent to alice.smith+tag@example.co.uk", "sent to ****"},
+		{"two emails", EmailPattern, "a@b.io, c@d.org", "****, ****"},
+		{"not an email", EmailPattern, "user@localhost", "user@localhost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Logger{RedactPatterns: []*regexp.Regexp{tt.pattern}}
+			if got := l.redactString(tt.in); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name       string
+		fields     []string
+		patterns   []*regexp.Regexp
+		data       LogData
+		extra      map[string]interface{}
+		want       LogData
+		wantFields map[string]interface{}
+		wantExtra  map[string]interface{}
+	}{
+		{
+			name: "none set",
+			data: LogData{ParamA: "4111111111111111"},
+			want: LogData{ParamA: "4111111111111111"},
+		},
+		{
+			name:   "named LogData field",
+			fields: []string{"param_a", "RRN"},
+			data:   LogData{ParamA: "secret", RRN: "123", ParamB: "kept"},
+			want:   LogData{ParamA: redactedValue, RRN: redactedValue, ParamB: "kept"},
+		},
+		{
+			name:   "empty value left empty",
+			fields: []string{"param_a"},
+			data:   LogData{},
+			want:   LogData{},
+		},
+		{
+			name:       "named custom field",
+			fields:     []string{"_Token"},
+			data:       LogData{Fields: map[string]interface{}{"token": "abc", "count": 3, "other": "kept"}},
+			wantFields: map[string]interface{}{"token": redactedValue, "count": 3, "other": "kept"},
+		},
+		{
+			name:       "named non-string field",
+			fields:     []string{"pin"},
+			data:       LogData{Fields: map[string]interface{}{"pin": 1234}},
+			wantFields: map[string]interface{}{"pin": redactedValue},
+		},
+		{
+			name:     "patterns in every string",
+			patterns: []*regexp.Regexp{PANPattern, EmailPattern},
+			data: LogData{
+				Message:     "card 4111111111111111",
+				FullMessage: "mail bob@example.com",
+				DeviceInfo:  "4111 1111 1111 1111",
+				Fields:      map[string]interface{}{"note": "from a@b.io", "amount": 42.5},
+			},
+			want: LogData{
+				Message:     "card ****",
+				FullMessage: "mail ****",
+				DeviceInfo:  "****",
+			},
+			wantFields: map[string]interface{}{"note": "from ****", "amount": 42.5},
+		},
+		{
+			name:      "extras named and matched",
+			fields:    []string{"error_cause"},
+			patterns:  []*regexp.Regexp{EmailPattern},
+			extra:     map[string]interface{}{"_error_cause": "no such user", "_stacktrace": "user a@b.io"},
+			wantExtra: map[string]interface{}{"_error_cause": redactedValue, "_stacktrace": "user ****"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Logger{RedactFields: tt.fields, RedactPatterns: tt.patterns}
+			data := tt.data
+			data.extra = tt.extra
+			callerFields := maps.Clone(tt.data.Fields)
+			l.redact(&data)
+
+			gotFields, gotExtra := data.Fields, data.extra
+			data.Fields, data.extra = nil, nil
+			if !reflect.DeepEqual(data, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", data, tt.want)
+			}
+			wantFields := tt.wantFields
+			if wantFields == nil {
+				wantFields = tt.data.Fields
+			}
+			if !maps.Equal(gotFields, wantFields) {
+				t.Errorf("Fields = %v, want %v", gotFields, wantFields)
+			}
+			wantExtra := tt.wantExtra
+			if wantExtra == nil {
+				wantExtra = tt.extra
+			}
+			if !maps.Equal(gotExtra, wantExtra) {
+				t.Errorf("extras = %v, want %v", gotExtra, wantExtra)
+			}
+			if !maps.Equal(tt.data.Fields, callerFields) {
+				t.Errorf("caller's Fields changed to %v", tt.data.Fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// LogVia is like LogContext, but sends the message over protocol, as for
+// Logger.Protocol, to GraylogHost instead of the Logger's own
+// protocol, and waits for the send even if the Logger is async. The other
+// settings, including the port, are the Logger's. An empty protocol means
+// the Logger's own.
+func (l *Logger) LogVia(ctx context.Context, protocol string, level Level, message string, data LogData) error {
+	if protocol == "" {
+		protocol = l.Protocol
+	}
+	return l.logVia(ctx, protocol, level, message, data)
+}
+
+// LogReliable is like LogContext, but sends the message over TCP to
+// GraylogHost even if the Logger uses UDP, and waits for the send even if it
+// is async, so the returned error says whether the message reached a
+// connection. Use it for the few messages, such as audit logs, that must not
+// be lost silently. Graylog needs a GELF TCP input on GraylogPort.
+func (l *Logger) LogReliable(ctx context.Context, level Level, message string, data LogData) error {
+	return l.logVia(ctx, "tcp", level, message, data)
+}
+
+// overrides are the endpoints used for messages sent over another protocol
+// than the Logger's by LogVia, created on first use and shared by derived
+// loggers
+type overrides struct {
+	mu        sync.Mutex
+	endpoints map[string]*Logger
+}
+
+// endpoint returns l's endpoint for protocol, which is l itself for l's own
+// protocol
+func (o *overrides) endpoint(l *Logger, protocol string) (*Logger, error) {
+	protocol = normalizeProtocol(protocol)
+	if protocol == l.Protocol {
+		return l, nil
+	}
+	if !validProtocol(protocol) {
+		return nil, fmt.Errorf("graylog: invalid protocol %q", protocol)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if e, ok := o.endpoints[protocol]; ok {
+		return e, nil
+	}
+
+	e := *l
+	e.Protocol = protocol
+	if err := e.validateFormat(); err != nil {
+		return nil, err
+	}
+	e.Destinations = nil
+	e.endpoints = nil
+	e.breaker = &breaker{}
+	e.conn = &connection{}
+	e.async = nil
+	e.reconnect = nil // LogVia reports send errors
+	if isHTTP(protocol) && !isHTTP(l.Protocol) {
+		e.HTTPClient = nil // connect builds one
+	}
+	e.connect()
+
+	if o.endpoints == nil {
+		o.endpoints = map[string]*Logger{}
+	}
+	o.endpoints[protocol] = &e
+	return &e, nil
+}
+
+// close closes the connections of the endpoints created so far
+func (o *overrides) close() []error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var errs []error
+	for _, e := range o.endpoints {
+		errs = append(errs, e.conn.close())
+	}
+	return errs
+}
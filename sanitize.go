@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sanitize replaces invalid UTF-8 and strips control characters from the
+// string fields of data, custom fields and the logger's own included
+func sanitize(data *LogData) {
+	for _, value := range data.stringFields() {
+		*value = sanitizeString(*value)
+	}
+	data.Fields = sanitizeFields(data.Fields)
+	data.extra = sanitizeFields(data.extra)
+}
+
+// sanitizeFields sanitizes the string values of fields, copying the map if
+// any needs it so the caller's is not modified
+func sanitizeFields(fields map[string]interface{}) map[string]interface{} {
+	dirty := false
+	for _, value := range fields {
+		if s, ok := value.(string); ok && !isClean(s) {
+			dirty = true
+			break
+		}
+	}
+	if !dirty {
+		return fields
+	}
+
+	sanitized := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if s, ok := value.(string); ok {
+			value = sanitizeString(s)
+		}
+		sanitized[key] = value
+	}
+	return sanitized
+}
+
+// sanitizeString replaces invalid UTF-8 sequences with U+FFFD and removes
+// control characters other than newline and tab
+func sanitizeString(s string) string {
+	if isClean(s) {
+		return s
+	}
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	return strings.Map(func(r rune) rune {
+		if isStripped(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// isClean reports whether s is valid UTF-8 without stripped characters
+func isClean(s string) bool {
+	return utf8.ValidString(s) && !strings.ContainsFunc(s, isStripped)
+}
+
+// isStripped reports whether sanitizing removes r
+func isStripped(r rune) bool {
+	return r != '\n' && r != '\t' && unicode.IsControl(r)
+}
@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+)
+
+// slogHandler adapts a Logger to slog.Handler. Attributes become custom
+// fields, with group names joined to the key by dots.
+type slogHandler struct {
+	logger *Logger
+	fields map[string]interface{} // attributes from WithAttrs
+	prefix string                 // open groups, e.g. "request."
+}
+
+// NewSlogHandler returns a slog.Handler that ships records through l, so
+// slog.New(logger.NewSlogHandler(l)) logs to Graylog
+func NewSlogHandler(l *Logger) slog.Handler {
+	return &slogHandler{logger: l, fields: map[string]interface{}{}}
+}
+
+// Enabled reports whether l logs records at level
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Enabled(fromSlogLevel(level))
+}
+
+// Handle logs r with its attributes as custom fields
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := maps.Clone(h.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(fields, h.prefix, a)
+		return true
+	})
+	return h.logger.LogContext(ctx, fromSlogLevel(r.Level), r.Message, LogData{Fields: fields})
+}
+
+// WithAttrs returns a handler that adds attrs to every record
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := maps.Clone(h.fields)
+	for _, a := range attrs {
+		addAttr(fields, h.prefix, a)
+	}
+	return &slogHandler{logger: h.logger, fields: fields, prefix: h.prefix}
+}
+
+// WithGroup returns a handler that prefixes later attribute keys with name
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, fields: h.fields, prefix: h.prefix + name + "."}
+}
+
+// addAttr stores a in fields under prefix, flattening groups
+func addAttr(fields map[string]interface{}, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addAttr(fields, prefix, ga)
+		}
+		return
+	}
+
+	value := a.Value.Any()
+	if err, ok := value.(error); ok {
+		value = err.Error() // errors marshal to {} otherwise
+	}
+	fields[prefix+a.Key] = value
+}
+
+// fromSlogLevel maps a slog level to the nearest Level at or below it
+func fromSlogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelDebug:
+		return LevelTrace
+	case level < slog.LevelInfo:
+		return LevelDebug
+	case level < slog.LevelWarn:
+		return LevelInfo
+	case level < slog.LevelError:
+		return LevelWarn
+	default:
+		return LevelError
+	}
+}
@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// spoolReplayInterval is how often the spool is replayed to Graylog
+const spoolReplayInterval = 10 * time.Second
+
+// ErrSpoolFull is reported when a failed payload doesn't fit in the spool
+// file and is dropped
+var ErrSpoolFull = errors.New("graylog: spool file full, message dropped")
+
+// spool keeps messages that could not be sent in a file and replays them
+// once Graylog is reachable again. Each is stored as a "<length> <message>\n"
+// record, so messages may contain newlines, as RFC 5424 ones do.
+type spool struct {
+	path     string
+	maxBytes int64 // zero means no limit
+
+	mu       sync.Mutex // guards the file
+	stopOnce sync.Once  // a Logger and its children may each call Close
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newSpool(path string, maxBytes int64) *spool {
+	return &spool{
+		path:     path,
+		maxBytes: maxBytes,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// write appends messages to the spool file as records, all or none of them
+func (s *spool) write(messages [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("graylog: open spool: %w", err)
+	}
+	defer f.Close()
+
+	var size int64
+	if s.maxBytes > 0 {
+		info, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("graylog: open spool: %w", err)
+		}
+		size = info.Size()
+	}
+
+	// A fresh buffer rather than the messages' spare capacity, which
+	// sendTCP may be reading
+	var records []byte
+	for _, message := range messages {
+		records = strconv.AppendInt(records, int64(len(message)), 10)
+		records = append(records, ' ')
+		records = append(records, message...)
+		records = append(records, '\n')
+	}
+	if s.maxBytes > 0 && size+int64(len(records)) > s.maxBytes {
+		return ErrSpoolFull
+	}
+	if _, err := f.Write(records); err != nil {
+		return fmt.Errorf("graylog: write spool: %w", err)
+	}
+	return nil
+}
+
+// read returns the spooled messages
+func (s *spool) read() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("graylog: read spool: %w", err)
+	}
+	return data, nil
+}
+
+// discard removes the first n bytes, already replayed, keeping anything
+// spooled since read
+func (s *spool) discard(n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("graylog: read spool: %w", err)
+	}
+	if err := os.WriteFile(s.path, data[min(n, len(data)):], 0o600); err != nil {
+		return fmt.Errorf("graylog: write spool: %w", err)
+	}
+	return nil
+}
+
+// close stops replaying. It is safe to call more than once.
+func (s *spool) close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// spoolPayload saves a payload holding the given number of messages whose
+// send failed, for replaySpool to retry. A batch is split into its
+// messages, so each is replayed on its own.
+func (l *Logger) spoolPayload(payload []byte, messages int) error {
+	records := [][]byte{payload}
+	if messages > 1 {
+		records = bytes.Split(payload, []byte{l.delimiter()})
+	}
+	err := l.spool.write(records)
+	if errors.Is(err, ErrSpoolFull) {
+		l.counters.dropped.Add(uint64(len(records)))
+	}
+	if err != nil && l.OnError != nil {
+		l.OnError(payload, err)
+	}
+	return err
+}
+
+// runSpool replays the spool every spoolReplayInterval until Close
+func (l *Logger) runSpool() {
+	defer close(l.spool.done)
+
+	// Abort a replay blocked on Graylog as soon as Close is called
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-l.spool.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(spoolReplayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.replaySpool(ctx)
+		case <-l.spool.stop:
+			return
+		}
+	}
+}
+
+// replaySpool sends spooled messages one at a time, stopping at the first
+// failure so Graylog is not flooded while it is still down. A message is
+// only removed from the spool once sent, so a crash mid-replay repeats it
+// rather than losing it. Messages go to every endpoint, so one that never
+// failed may see duplicates.
+func (l *Logger) replaySpool(ctx context.Context) {
+	data, err := l.spool.read()
+	if err != nil {
+		if l.OnError != nil {
+			l.OnError(nil, err)
+		}
+		return
+	}
+
+	sent := 0
+	for sent < len(data) {
+		message, size := spoolRecord(data[sent:])
+		if len(message) > 0 {
+			if err := l.deliver(ctx, message, 1); err != nil {
+				break
+			}
+		}
+		sent += size
+	}
+
+	if sent > 0 {
+		if err := l.spool.discard(sent); err != nil && l.OnError != nil {
+			l.OnError(nil, err)
+		}
+	}
+}
+
+// spoolRecord returns the message in the first record of data and the
+// number of bytes the record takes up. A line that isn't a record, as
+// written by versions that spooled one message per line, is returned whole.
+func spoolRecord(data []byte) (message []byte, size int) {
+	if head, rest, ok := bytes.Cut(data, []byte{' '}); ok && len(head) <= 10 {
+		if n, err := strconv.Atoi(string(head)); err == nil && n >= 0 && n < len(rest) && rest[n] == '\n' {
+			return rest[:n], len(head) + 1 + n + 1
+		}
+	}
+	line, _, _ := bytes.Cut(data, []byte{'\n'})
+	return line, len(line) + 1
+}
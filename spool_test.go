@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCloseTwiceWithSpool(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(l, child *Logger) []error
+	}{
+		{"same logger", func(l, _ *Logger) []error { return []error{l.Close(), l.Close()} }},
+		{"child then parent", func(l, child *Logger) []error { return []error{child.Close(), l.Close()} }},
+		{"parent then child", func(l, child *Logger) []error { return []error{l.Close(), child.Close()} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLoggerWithOptions(
+				WithGraylog("127.0.0.1", "12201", "udp"),
+				WithSpool(filepath.Join(t.TempDir(), "spool"), 0),
+				WithDisabledIPLookup(),
+				WithDisabledLocal(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			child := l.WithFields(LogData{AppName: "child"})
+			for i, err := range tt.close(l, child) {
+				if err != nil {
+					t.Errorf("Close #%d: %v", i+1, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSpoolRecord(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantMessage string
+		wantSize    int
+	}{
+		{"record", "5 hello\nnext", "hello", 8},
+		{"newline inside", "11 line1\nline2\n", "line1\nline2", 15},
+		{"empty", "0 \n", "", 3},
+		{"legacy line", `{"a":1}` + "\n" + `{"b":2}`, `{"a":1}`, 8},
+		{"legacy last line", `{"a":1}`, `{"a":1}`, 8},
+		{"length too long", "9 hi\n", "9 hi", 5},
+		{"no newline after", "2 hello\n", "2 hello", 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, size := spoolRecord([]byte(tt.data))
+			if string(message) != tt.wantMessage || size != tt.wantSize {
+				t.Errorf("got %q, %d, want %q, %d", message, size, tt.wantMessage, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestSpoolReplay(t *testing.T) {
+	syslogLine := "<14>1 2023-11-14T22:13:20.000000Z web-1 app 1 - - line1\nline2"
+	tests := []struct {
+		name     string
+		opts     []Option
+		legacy   string // written to the spool file as is
+		payload  string
+		messages int
+		want     []string
+	}{
+		{
+			"rfc5424 over udp",
+			[]Option{WithGraylog("graylog", "514", "udp"), WithFormat(FormatRFC5424)},
+			"", syslogLine, 1,
+			[]string{syslogLine},
+		},
+		{
+			"rfc5424 over tcp",
+			[]Option{WithGraylog("graylog", "514", "tcp"), WithFormat(FormatRFC5424)},
+			"", syslogLine, 1,
+			[]string{"61 " + syslogLine},
+		},
+		{
+			"gelf batch",
+			[]Option{WithGraylog("graylog", "12201", "tcp")},
+			"", `{"a":1}` + "\n" + `{"b":2}`, 2,
+			[]string{`{"a":1}` + "\n", `{"b":2}` + "\n"},
+		},
+		{
+			"gelf batch, null delimiter",
+			[]Option{WithGraylog("graylog", "12201", "tcp"), WithNullDelimiter()},
+			"", `{"a":1}` + "\x00" + `{"b":2}`, 2,
+			[]string{`{"a":1}` + "\x00", `{"b":2}` + "\x00"},
+		},
+		{
+			"legacy lines",
+			[]Option{WithGraylog("graylog", "12201", "tcp")},
+			`{"a":1}` + "\n" + `{"b":2}` + "\n", `{"c":3}`, 1,
+			[]string{`{"a":1}` + "\n", `{"b":2}` + "\n", `{"c":3}` + "\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "spool")
+			if tt.legacy != "" {
+				if err := os.WriteFile(path, []byte(tt.legacy), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			frames := &dryRunFrames{}
+			l, err := NewLoggerWithOptions(append(tt.opts,
+				WithDryRun(frames.record),
+				WithSpool(path, 0),
+				WithDisabledIPLookup(),
+				WithDisabledLocal(),
+			)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			if err := l.spoolPayload([]byte(tt.payload), tt.messages); err != nil {
+				t.Fatal(err)
+			}
+			l.replaySpool(context.Background())
+
+			var got []string
+			for _, frame := range frames.get() {
+				got = append(got, string(frame))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("replayed %q, want %q", got, tt.want)
+			}
+			if left, _ := os.ReadFile(path); len(left) != 0 {
+				t.Errorf("spool still holds %q", left)
+			}
+		})
+	}
+}
+
+func TestSpoolFull(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool")
+	l, err := NewLoggerWithOptions(
+		WithGraylog("graylog", "12201", "tcp"),
+		WithDryRun(nil),
+		WithSpool(path, 20),
+		WithDisabledIPLookup(),
+		WithDisabledLocal(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if err := l.spoolPayload([]byte(`{"a":1}`), 1); err != nil {
+		t.Fatal(err)
+	}
+	// The batch doesn't fit as a whole, so none of it is written
+	if err := l.spoolPayload([]byte(`{"b":2}`+"\n"+`{"c":3}`), 2); err != ErrSpoolFull {
+		t.Errorf("spoolPayload = %v, want %v", err, ErrSpoolFull)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, []byte("7 {\"a\":1}\n")) {
+		t.Errorf("spool holds %q", data)
+	}
+	if dropped := l.Stats().Dropped; dropped != 2 {
+		t.Errorf("Dropped = %d, want 2", dropped)
+	}
+}
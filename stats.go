@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of a Logger's delivery counters
+type Stats struct {
+	Sent        uint64 // messages delivered to Graylog
+	Failed      uint64 // messages whose send failed after retries
+	Dropped     uint64 // messages dropped by a full buffer or spool, or rate limiting
+	Overflowed  uint64 // of Dropped, messages dropped by a full async or reconnect buffer
+	BytesSent   uint64 // bytes written to Graylog, after compression
+	BufferDepth int    // messages waiting in the async buffer
+	Reconnects  uint64 // connections to Graylog re-established after being dropped
+
+	// SendLatency summarizes the duration of recent sends to Graylog
+	SendLatency LatencySummary
+}
+
+// LatencySummary holds percentiles of the last latencyWindow send
+// durations, retries included. It is zero until something was sent.
+type LatencySummary struct {
+	P50, P95, P99 time.Duration
+}
+
+// latencyWindow is how many recent send durations LatencySummary covers
+const latencyWindow = 1024
+
+// latencyRing keeps the most recent send durations
+type latencyRing struct {
+	mu        sync.Mutex
+	durations [latencyWindow]time.Duration
+	n         int // durations recorded so far, capped at latencyWindow
+	next      int // index the next duration goes to
+}
+
+func (r *latencyRing) record(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.durations[r.next] = d
+	r.next = (r.next + 1) % latencyWindow
+	r.n = min(r.n+1, latencyWindow)
+}
+
+// summary computes the percentiles of the recorded durations
+func (r *latencyRing) summary() LatencySummary {
+	r.mu.Lock()
+	durations := slices.Clone(r.durations[:r.n])
+	r.mu.Unlock()
+	if len(durations) == 0 {
+		return LatencySummary{}
+	}
+
+	slices.Sort(durations)
+	percentile := func(p int) time.Duration {
+		return durations[(len(durations)-1)*p/100]
+	}
+	return LatencySummary{P50: percentile(50), P95: percentile(95), P99: percentile(99)}
+}
+
+// counters are the live values behind Stats, shared by derived loggers
+type counters struct {
+	sent    atomic.Uint64
+	failed  atomic.Uint64
+	dropped atomic.Uint64
+	// overflowed counts the drops by a full async or reconnect buffer
+	overflowed atomic.Uint64
+	latency    latencyRing
+	bytes      atomic.Uint64
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+func (c *counters) setLastError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastErr = err
+}
+
+// LastError returns the error of the most recent failed send to Graylog,
+// or nil if none has failed yet. A later successful send doesn't clear it;
+// compare Stats or ConnectedSince to tell whether delivery has recovered.
+func (l *Logger) LastError() error {
+	l.counters.mu.Lock()
+	defer l.counters.mu.Unlock()
+	return l.counters.lastErr
+}
+
+// ConnectedSince returns when the current connection to Graylog was
+// established, or the zero time while there is none. The HTTP transports
+// don't hold a connection, so for them it is always zero.
+func (l *Logger) ConnectedSince() time.Time {
+	return l.conn.connectedSince()
+}
+
+// sendObservers are the callbacks registered with AddSendObserver
+type sendObservers struct {
+	mu  sync.RWMutex
+	fns []func(duration time.Duration, messages int, err error)
+}
+
+// AddSendObserver registers fn to be called after every send to Graylog
+// with its duration, retries included, the number of messages sent and the
+// error, if any. It is how metrics backends such as the promlogger package
+// track send latency.
+func (l *Logger) AddSendObserver(fn func(duration time.Duration, messages int, err error)) {
+	l.observers.mu.Lock()
+	defer l.observers.mu.Unlock()
+	l.observers.fns = append(l.observers.fns, fn)
+}
+
+// observeSend records a finished send's duration and reports the send to
+// the registered observers
+func (l *Logger) observeSend(duration time.Duration, messages int, err error) {
+	l.counters.latency.record(duration)
+	l.observers.mu.RLock()
+	defer l.observers.mu.RUnlock()
+	for _, fn := range l.observers.fns {
+		fn(duration, messages, err)
+	}
+}
+
+// Stats returns the current delivery counters. It is safe to call while
+// logging is in progress.
+func (l *Logger) Stats() Stats {
+	stats := Stats{
+		Sent:        l.counters.sent.Load(),
+		Failed:      l.counters.failed.Load(),
+		Dropped:     l.counters.dropped.Load() + l.limiter.dropped.Load(),
+		BytesSent:   l.counters.bytes.Load(),
+		Overflowed:  l.counters.overflowed.Load(),
+		SendLatency: l.counters.latency.summary(),
+	}
+	if l.async != nil {
+		stats.BufferDepth = len(l.async.queue)
+	}
+	stats.Reconnects = l.conn.reconnects.Load()
+	for _, e := range l.endpoints {
+		stats.Reconnects += e.conn.reconnects.Load()
+	}
+	return stats
+}
@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats for Logger.Format
+const (
+	FormatGELF    = "gelf"
+	FormatRFC5424 = "rfc5424"
+)
+
+// syslogSDID is the structured data element carrying the additional
+// fields. 32473 is the private enterprise number reserved for examples
+// and documentation.
+const syslogSDID = "fields@32473"
+
+// syslogFacility is the syslog facility messages are sent with: user-level
+const syslogFacility = 1
+
+// validateFormat checks that Format is known and, for RFC 5424, that the
+// protocol can carry it
+func (l *Logger) validateFormat() error {
+	switch l.Format {
+	case "", FormatGELF:
+		return nil
+	case FormatRFC5424:
+		if isHTTP(l.Protocol) {
+			return fmt.Errorf("graylog: format %s needs a udp, tcp or unix protocol, not %s", l.Format, l.Protocol)
+		}
+		return nil
+	}
+	return fmt.Errorf("graylog: invalid format %q", l.Format)
+}
+
+// syslog reports whether messages are sent as RFC 5424 syslog
+func (l *Logger) syslog() bool {
+	return l.Format == FormatRFC5424
+}
+
+// rfc5424 formats a GELF document as an RFC 5424 syslog message. The
+// additional fields go in one structured data element, sorted by name, and
+// the message is full_message if present, else short_message.
+func rfc5424(level Level, doc map[string]interface{}) []byte {
+	timestamp := time.Now()
+	if ts, ok := doc["timestamp"].(float64); ok {
+		timestamp = time.UnixMicro(int64(math.Round(ts * 1e6)))
+	}
+
+	b := make([]byte, 0, 256)
+	b = fmt.Appendf(b, "<%d>1 %s %s %s %d - ",
+		syslogFacility*8+level.syslog(),
+		timestamp.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(doc["host"], 255),
+		syslogHeaderField(doc["_appname"], 48),
+		pid)
+
+	var names []string
+	for key := range doc {
+		if strings.HasPrefix(key, "_") {
+			names = append(names, key)
+		}
+	}
+	if len(names) == 0 {
+		b = append(b, '-')
+	} else {
+		slices.Sort(names)
+		b = append(b, '[')
+		b = append(b, syslogSDID...)
+		for _, key := range names {
+			name := syslogParamName(key[1:])
+			if name == "" {
+				continue
+			}
+			b = append(b, ' ')
+			b = append(b, name...)
+			b = append(b, `="`...)
+			b = appendSyslogParamValue(b, syslogString(doc[key]))
+			b = append(b, '"')
+		}
+		b = append(b, ']')
+	}
+
+	message, ok := doc["full_message"].(string)
+	if !ok {
+		message, _ = doc["short_message"].(string)
+	}
+	if message != "" {
+		b = append(b, ' ')
+		b = append(b, message...)
+	}
+	return b
+}
+
+// syslogHeaderField returns a header field value as printable ASCII of at
+// most limit characters, or the nil value "-" if it is empty
+func syslogHeaderField(value interface{}, limit int) string {
+	s, _ := value.(string)
+	s = strings.Map(func(r rune) rune {
+		if r > ' ' && r <= '~' {
+			return r
+		}
+		return -1
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s[:min(len(s), limit)]
+}
+
+// syslogParamName makes a field name a valid SD-PARAM name: at most 32
+// printable ASCII characters other than '=', ' ', ']' and '"'
+func syslogParamName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	return name[:min(len(name), 32)]
+}
+
+// appendSyslogParamValue appends s escaped as an SD-PARAM value
+func appendSyslogParamValue(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\\', ']':
+			b = append(b, '\\')
+		}
+		b = append(b, s[i])
+	}
+	return b
+}
+
+// syslogString formats a GELF field value for structured data
+func syslogString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// frameSyslog frames a syslog message for a stream with octet counting,
+// as RFC 6587 describes, so messages may contain newlines
+func frameSyslog(data []byte) []byte {
+	framed := make([]byte, 0, len(data)+8)
+	framed = strconv.AppendInt(framed, int64(len(data)), 10)
+	framed = append(framed, ' ')
+	return append(framed, data...)
+}
@@ -0,0 +1,151 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFormatOption(t *testing.T) {
+	tests := []struct {
+		format   string
+		protocol string
+		want     string
+		wantErr  bool
+	}{
+		{"", "udp", "", false},
+		{"gelf", "tcp", FormatGELF, false},
+		{"GELF", "tcp", FormatGELF, false},
+		{" RFC5424 ", "udp", FormatRFC5424, false},
+		{"rfc5424", "http", "", true},
+		{"xml", "udp", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" over "+tt.protocol, func(t *testing.T) {
+			l, err := NewLoggerWithOptions(
+				WithGraylog("127.0.0.1", "12201", tt.protocol),
+				WithFormat(tt.format),
+				WithDryRun(nil),
+				WithDisabledIPLookup(),
+				WithDisabledLocal(),
+			)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("no error for format %q over %s", tt.format, tt.protocol)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Format != tt.want {
+				t.Errorf("Format = %q, want %q", l.Format, tt.want)
+			}
+		})
+	}
+}
+
+func TestRFC5424(t *testing.T) {
+	header := func(severity int, host, app string) string {
+		return fmt.Sprintf("<%d>1 2023-11-14T22:13:20.123456Z %s %s %d - ", 8+severity, host, app, pid)
+	}
+	base := func(fields map[string]interface{}) map[string]interface{} {
+		doc := map[string]interface{}{
+			"version":       "1.1",
+			"host":          "web-1",
+			"short_message": "hello",
+			"timestamp":     1700000000.123456,
+			"level":         6,
+		}
+		for key, value := range fields {
+			doc[key] = value
+		}
+		return doc
+	}
+
+	tests := []struct {
+		name  string
+		level Level
+		doc   map[string]interface{}
+		want  string
+	}{
+		{"no fields", LevelInfo, base(nil), header(6, "web-1", "-") + "- hello"},
+		{"severity", LevelError, base(nil), header(3, "web-1", "-") + "- hello"},
+		{
+			"fields sorted",
+			LevelInfo,
+			base(map[string]interface{}{"_appname": "payments", "_b": 2, "_a": "x"}),
+			header(6, "web-1", "payments") + `[fields@32473 a="x" appname="payments" b="2"] hello`,
+		},
+		{
+			"value escaping",
+			LevelInfo,
+			base(map[string]interface{}{"_v": `a]b"c\d`}),
+			header(6, "web-1", "-") + `[fields@32473 v="a\]b\"c\\d"] hello`,
+		},
+		{
+			"name cleanup",
+			LevelInfo,
+			base(map[string]interface{}{"_a b=c]\"d": 1}),
+			header(6, "web-1", "-") + `[fields@32473 abcd="1"] hello`,
+		},
+		{
+			"multi-line message",
+			LevelInfo,
+			base(map[string]interface{}{"full_message": "line1\nline2"}),
+			header(6, "web-1", "-") + "- line1\nline2",
+		},
+		{
+			"header fields",
+			LevelInfo,
+			base(map[string]interface{}{"host": "my host\n", "_appname": strings.Repeat("a", 60)}),
+			header(6, "myhost", strings.Repeat("a", 48)) + `[fields@32473 appname="` + strings.Repeat("a", 60) + `"] hello`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(rfc5424(tt.level, tt.doc)); got != tt.want {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRFC5424Framing(t *testing.T) {
+	tests := []struct {
+		protocol string
+		framed   func(message string) string
+	}{
+		{"udp", func(m string) string { return m }},
+		{"tcp", func(m string) string { return fmt.Sprintf("%d %s", len(m), m) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			frames := &dryRunFrames{}
+			l, err := NewLoggerWithOptions(
+				WithGraylog("graylog", "514", tt.protocol),
+				WithFormat(FormatRFC5424),
+				WithDryRun(frames.record),
+				WithDisabledIPLookup(),
+				WithDisabledLocal(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Info("line1\nline2", LogData{}); err != nil {
+				t.Fatal(err)
+			}
+			got := frames.get()
+			if len(got) != 1 {
+				t.Fatalf("%d frames, want 1", len(got))
+			}
+			message := string(got[0])
+			if tt.protocol == "tcp" {
+				_, message, _ = strings.Cut(message, " ")
+			}
+			if !strings.HasSuffix(message, " line1\nline2") || string(got[0]) != tt.framed(message) {
+				t.Errorf("frame %q", got[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || aix || zos || darwin || dragonfly || freebsd || netbsd || openbsd || windows)
+
+package logger
+
+// isTerminalFd reports whether fd is a terminal, which is never assumed on
+// platforms without a check
+func isTerminalFd(uintptr) bool {
+	return false
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package logger
+
+import "golang.org/x/sys/windows"
+
+// isTerminalFd reports whether fd is a console, enabling the escape
+// sequences colored output needs
+func isTerminalFd(fd uintptr) bool {
+	handle := windows.Handle(fd)
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
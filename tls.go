@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// NewTLSConfig builds a TLS config for the TCP transport. caFile adds custom
+// CA roots used to verify Graylog; certFile and keyFile set a client
+// certificate; serverName overrides the name checked against the server's
+// certificate, which defaults to GraylogHost. Empty arguments are skipped.
+func NewTLSConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: serverName}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// tlsConfig returns the TLS config to dial with, or nil for plain TCP and
+// default HTTPS verification
+func (l *Logger) tlsConfig() *tls.Config {
+	if (l.Protocol != "tcp" && l.Protocol != "https") || (l.TLSConfig == nil && !l.TLSInsecureSkipVerify) {
+		return nil
+	}
+
+	cfg := &tls.Config{}
+	if l.TLSConfig != nil {
+		cfg = l.TLSConfig.Clone()
+	}
+	if l.TLSInsecureSkipVerify {
+		cfg.InsecureSkipVerify = true
+	}
+	return cfg
+}
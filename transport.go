@@ -0,0 +1,411 @@
+package logger
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// GELF UDP chunking limits
+const (
+	maxChunkSize    = 8192 // largest datagram Graylog accepts
+	chunkHeaderSize = 12   // magic bytes, message ID, sequence number and count
+	maxChunks       = 128  // most chunks allowed per message
+)
+
+// Retry backoff bounds
+const (
+	defaultRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff     = 10 * time.Second
+)
+
+// defaultWriteTimeout bounds TCP writes when no timeout is configured
+const defaultWriteTimeout = 10 * time.Second
+
+// connection holds the long-lived socket to Graylog
+type connection struct {
+	mu   sync.Mutex
+	conn net.Conn
+
+	since      time.Time // when conn was dialed
+	connected  bool      // a dial has succeeded before
+	reconnects atomic.Uint64
+}
+
+// dial opens the socket with dialFunc if it is not already open
+func (c *connection) dial(dialFunc func() (net.Conn, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dialLocked(dialFunc)
+}
+
+func (c *connection) dialLocked(dialFunc func() (net.Conn, error)) error {
+	if c.conn != nil {
+		return nil
+	}
+	conn, err := dialFunc()
+	if err != nil {
+		return err
+	}
+	if c.connected {
+		c.reconnects.Add(1)
+	}
+	c.conn = conn
+	c.since = time.Now()
+	c.connected = true
+	return nil
+}
+
+// connectedSince returns when the open socket was dialed, or the zero time
+// if there is none
+func (c *connection) connectedSince() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.since
+}
+
+// close closes the socket; a later send will dial again
+func (c *connection) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeLocked()
+}
+
+func (c *connection) closeLocked() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	c.since = time.Time{}
+	return err
+}
+
+// write runs send on the socket, dialing first if needed. On a write error
+// the socket is dropped and a single reconnect is attempted. This also
+// covers UDP: after an ICMP port unreachable, e.g. while Graylog's input
+// restarts, the next write on the socket fails with ECONNREFUSED without
+// sending anything, and a fresh socket sends it.
+func (c *connection) write(dialFunc func() (net.Conn, error), send func(net.Conn) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.writeOnce(dialFunc, send)
+	if err != nil {
+		c.closeLocked()
+		err = c.writeOnce(dialFunc, send)
+	}
+	return err
+}
+
+func (c *connection) writeOnce(dialFunc func() (net.Conn, error), send func(net.Conn) error) error {
+	if err := c.dialLocked(dialFunc); err != nil {
+		return err
+	}
+	return send(c.conn)
+}
+
+// host returns the Graylog host without IPv6 brackets, so "[::1]" is
+// accepted as well as "::1"
+func (l *Logger) host() string {
+	return strings.TrimSuffix(strings.TrimPrefix(l.GraylogHost, "["), "]")
+}
+
+// address returns the Graylog host:port, bracketing IPv6 hosts, or for the
+// Unix socket protocols the socket path held in GraylogHost
+func (l *Logger) address() string {
+	if isUnix(l.Protocol) {
+		return l.GraylogHost
+	}
+	return net.JoinHostPort(l.host(), l.GraylogPort)
+}
+
+// normalizeProtocol lower-cases and trims protocol, so "TCP" selects "tcp"
+func normalizeProtocol(protocol string) string {
+	return strings.ToLower(strings.TrimSpace(protocol))
+}
+
+// validProtocol reports whether protocol is one a Logger can send over
+func validProtocol(protocol string) bool {
+	switch protocol {
+	case "udp", "tcp", "unix", "unixgram":
+		return true
+	}
+	return isHTTP(protocol)
+}
+
+// isUnix reports whether protocol is one of the Unix domain socket
+// transports
+func isUnix(protocol string) bool {
+	return protocol == "unix" || protocol == "unixgram"
+}
+
+// isDatagram reports whether protocol sends messages as GELF UDP datagrams,
+// compressed and chunked; the stream protocols "tcp" and "unix" frame them
+// instead
+func isDatagram(protocol string) bool {
+	return protocol == "udp" || protocol == "unixgram"
+}
+
+// dial connects to Graylog with the configured protocol, bounded by the
+// configured timeout and ctx
+func (l *Logger) dial(ctx context.Context) (net.Conn, error) {
+	if l.DialFunc != nil {
+		return l.dialWith(ctx, l.DialFunc)
+	}
+	dialer := net.Dialer{Timeout: l.Timeout, KeepAlive: l.KeepAlive}
+	if cfg := l.tlsConfig(); cfg != nil {
+		tlsDialer := tls.Dialer{NetDialer: &dialer, Config: cfg}
+		return tlsDialer.DialContext(ctx, l.Protocol, l.address())
+	}
+	return dialer.DialContext(ctx, l.Protocol, l.address())
+}
+
+// dialWith connects with a custom DialFunc, doing the TLS handshake itself
+func (l *Logger) dialWith(ctx context.Context, dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)) (net.Conn, error) {
+	if l.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.Timeout)
+		defer cancel()
+	}
+	conn, err := dialFunc(ctx, l.Protocol, l.address())
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := l.tlsConfig()
+	if cfg == nil {
+		return conn, nil
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = l.host()
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// writeTimeout returns the bound on each write: WriteTimeout, else Timeout,
+// else for streams defaultWriteTimeout, so a half-open connection can't block
+// forever. Zero means no limit.
+func (l *Logger) writeTimeout() time.Duration {
+	switch {
+	case l.WriteTimeout > 0:
+		return l.WriteTimeout
+	case l.Timeout > 0:
+		return l.Timeout
+	case l.Protocol == "tcp" || l.Protocol == "unix":
+		return defaultWriteTimeout
+	}
+	return 0
+}
+
+// send writes data on conn with the protocol's framing, bounded by the
+// write timeout and ctx. A write that times out fails, and conn.write then
+// reconnects.
+func (l *Logger) send(ctx context.Context, conn net.Conn, data []byte) error {
+	var deadline time.Time // zero clears any deadline left by an earlier write
+	if timeout := l.writeTimeout(); timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	if err := conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+
+	// Abort a blocked write as soon as ctx is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetWriteDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	switch {
+	case l.syslog() && isDatagram(l.Protocol):
+		_, err := conn.Write(data)
+		return err
+	case l.syslog():
+		_, err := conn.Write(frameSyslog(data))
+		return err
+	case isDatagram(l.Protocol):
+		return sendUDP(conn, data)
+	}
+	return sendTCP(conn, data, l.delimiter())
+}
+
+// sendToEndpoint sends log data holding the given number of messages to
+// this Logger's Graylog endpoint using the selected protocol, retrying
+// failed sends with exponential backoff
+func (l *Logger) sendToEndpoint(ctx context.Context, logData []byte, messages int) error {
+	if l.reconnect != nil && l.buffer(logData, messages, false) {
+		return nil // queued behind the messages waiting for a reconnect
+	}
+	if err := ctx.Err(); err != nil {
+		l.counters.failed.Add(uint64(messages))
+		return err
+	}
+	if l.Timeout > 0 {
+		// Bound the whole send, reconnects and retries included
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.Timeout)
+		defer cancel()
+	}
+
+	address := l.address()
+	dial := func() (net.Conn, error) {
+		return l.dial(ctx)
+	}
+
+	payload := logData
+	algorithm := CompressionNone
+	if isDatagram(l.Protocol) || isHTTP(l.Protocol) {
+		// GELF TCP has no way to frame compressed payloads
+		var err error
+		algorithm = l.compression(len(logData))
+		payload, err = compress(algorithm, logData)
+		if err != nil {
+			l.counters.failed.Add(uint64(messages))
+			return fmt.Errorf("graylog: compress payload: %w", err)
+		}
+	}
+
+	if l.DryRun {
+		return l.dryRun(ctx, payload)
+	}
+
+	send := func() error {
+		return l.conn.write(dial, func(conn net.Conn) error {
+			return l.send(ctx, conn, payload)
+		})
+	}
+	if isHTTP(l.Protocol) {
+		send = func() error {
+			return l.sendHTTP(ctx, payload, algorithm)
+		}
+	}
+
+	err := ErrCircuitOpen
+	if l.breaker.allow(l.BreakerThreshold) {
+		start := time.Now()
+		for attempt := 0; ; attempt++ {
+			err = send()
+			if err == nil || attempt >= l.MaxRetries || !sleep(ctx, l.retryDelay(attempt)) {
+				break
+			}
+		}
+		l.observeSend(time.Since(start), messages, err)
+		l.breaker.record(l.BreakerThreshold, l.BreakerCooldown, err == nil)
+	}
+	if err != nil {
+		err = fmt.Errorf("graylog: send via %s to %s: %w", l.Protocol, address, err)
+		l.counters.setLastError(err)
+	}
+
+	if err != nil && l.reconnect != nil && l.buffer(logData, messages, true) {
+		return nil // sent once the connection is back
+	}
+	if err != nil {
+		l.counters.failed.Add(uint64(messages))
+		if l.OnError != nil {
+			l.OnError(logData, err)
+		}
+		return err
+	}
+
+	l.counters.sent.Add(uint64(messages))
+	l.counters.bytes.Add(uint64(len(payload)))
+	return nil
+}
+
+// retryDelay returns the backoff before retry number attempt+1: RetryBackoff
+// doubled per attempt, capped at maxRetryBackoff, with up to 50% jitter
+func (l *Logger) retryDelay(attempt int) time.Duration {
+	delay := l.RetryBackoff
+	if delay <= 0 {
+		delay = defaultRetryBackoff
+	}
+	for i := 0; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryBackoff)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// sleep waits for d, returning false if ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sendUDP sends log data over UDP, splitting messages that don't fit in a
+// single datagram into GELF chunks
+func sendUDP(conn net.Conn, data []byte) error {
+	if len(data) <= maxChunkSize {
+		_, err := conn.Write(data)
+		return err
+	}
+
+	chunkDataSize := maxChunkSize - chunkHeaderSize
+	count := (len(data) + chunkDataSize - 1) / chunkDataSize
+	if count > maxChunks {
+		return fmt.Errorf("message of %d bytes needs %d chunks, GELF allows at most %d", len(data), count, maxChunks)
+	}
+
+	messageID := binary.BigEndian.AppendUint64(nil, rand.Uint64())
+
+	chunk := make([]byte, 0, maxChunkSize)
+	for i := 0; i < count; i++ {
+		start := i * chunkDataSize
+		end := min(start+chunkDataSize, len(data))
+
+		chunk = append(chunk[:0], 0x1e, 0x0f) // GELF chunk magic bytes
+		chunk = append(chunk, messageID...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, data[start:end]...)
+
+		if _, err := conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delimiter returns the byte that ends each message on a stream
+func (l *Logger) delimiter() byte {
+	if l.NullDelimiter {
+		return 0
+	}
+	return '\n'
+}
+
+// sendTCP sends log data over TCP, ending it with delimiter. Data from
+// marshalGELF already has a newline just past its length, so with newline
+// framing it is only copied for other payloads, such as batches.
+func sendTCP(conn net.Conn, data []byte, delimiter byte) error {
+	framed := data[:len(data):len(data)]
+	if cap(data) > len(data) && data[:len(data)+1][len(data)] == delimiter {
+		framed = data[:len(data)+1]
+	} else {
+		framed = append(framed, delimiter)
+	}
+	_, err := conn.Write(framed)
+	return err
+}
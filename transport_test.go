@@ -0,0 +1,297 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testGraylog is a stream endpoint for a Logger using its dial method as
+// DialFunc. While down, dialing fails; otherwise each dial gets one end of
+// a net.Pipe and the messages written to it are collected.
+type testGraylog struct {
+	down atomic.Bool
+	hold chan struct{} // if set, reading starts once it is closed
+
+	mu       sync.Mutex
+	messages []string // short_message of each message received
+}
+
+func (g *testGraylog) dial(context.Context, string, string) (net.Conn, error) {
+	if g.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	go g.read(server)
+	return client, nil
+}
+
+func (g *testGraylog) read(conn net.Conn) {
+	if g.hold != nil {
+		<-g.hold
+	}
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		g.mu.Lock()
+		g.messages = append(g.messages, shortMessage(scanner.Bytes()))
+		g.mu.Unlock()
+	}
+}
+
+// shortMessage returns the short_message of a GELF payload
+func shortMessage(payload []byte) string {
+	var doc struct {
+		ShortMessage string `json:"short_message"`
+	}
+	json.Unmarshal(payload, &doc)
+	return doc.ShortMessage
+}
+
+// received returns the messages received so far
+func (g *testGraylog) received() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.messages...)
+}
+
+// wait returns the messages received once there are n of them, failing
+// the test if that takes too long
+func (g *testGraylog) wait(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := g.received()
+		if len(got) >= n {
+			return got
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %q, want %d messages", got, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// recordConn is a net.Conn that keeps the slices written to it, without
+// copying, so tests can check what was written and from where
+type recordConn struct {
+	net.Conn // nil; only the methods below are used
+	writes   [][]byte
+}
+
+func (c *recordConn) Write(p []byte) (int, error) {
+	c.writes = append(c.writes, p)
+	return len(p), nil
+}
+
+func (c *recordConn) SetWriteDeadline(time.Time) error { return nil }
+
+func TestSendUDPChunking(t *testing.T) {
+	chunkDataSize := maxChunkSize - chunkHeaderSize
+	tests := []struct {
+		name    string
+		size    int
+		chunks  int // zero for a single unchunked datagram
+		wantErr bool
+	}{
+		{"small", 100, 0, false},
+		{"exactly one datagram", maxChunkSize, 0, false},
+		{"one byte over", maxChunkSize + 1, 2, false},
+		{"maximum", maxChunks * chunkDataSize, maxChunks, false},
+		{"one chunk too many", maxChunks*chunkDataSize + 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			for i := range data {
+				data[i] = byte(i)
+			}
+			conn := &dryRunConn{}
+			err := sendUDP(conn, data)
+			if tt.wantErr {
+				if err == nil || len(conn.frames) != 0 {
+					t.Errorf("err = %v with %d datagrams sent, want an error and none", err, len(conn.frames))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.chunks == 0 {
+				if len(conn.frames) != 1 || !bytes.Equal(conn.frames[0], data) {
+					t.Errorf("got %d datagrams, want data unchanged in one", len(conn.frames))
+				}
+				return
+			}
+			if len(conn.frames) != tt.chunks {
+				t.Fatalf("got %d chunks, want %d", len(conn.frames), tt.chunks)
+			}
+			var reassembled []byte
+			messageID := conn.frames[0][2:10]
+			for i, chunk := range conn.frames {
+				if len(chunk) > maxChunkSize {
+					t.Errorf("chunk %d is %d bytes, above %d", i, len(chunk), maxChunkSize)
+				}
+				if chunk[0] != 0x1e || chunk[1] != 0x0f {
+					t.Errorf("chunk %d magic = %x, want 1e0f", i, chunk[:2])
+				}
+				if !bytes.Equal(chunk[2:10], messageID) {
+					t.Errorf("chunk %d message ID = %x, want %x", i, chunk[2:10], messageID)
+				}
+				if int(chunk[10]) != i || int(chunk[11]) != tt.chunks {
+					t.Errorf("chunk %d sequence = %d/%d, want %d/%d", i, chunk[10], chunk[11], i, tt.chunks)
+				}
+				reassembled = append(reassembled, chunk[chunkHeaderSize:]...)
+			}
+			if !bytes.Equal(reassembled, data) {
+				t.Error("reassembled chunks differ from data")
+			}
+		})
+	}
+}
+
+func TestSendUDPMessageIDs(t *testing.T) {
+	data := make([]byte, maxChunkSize+1)
+	ids := map[uint64]bool{}
+	for range 10 {
+		conn := &dryRunConn{}
+		if err := sendUDP(conn, data); err != nil {
+			t.Fatal(err)
+		}
+		ids[binary.BigEndian.Uint64(conn.frames[0][2:10])] = true
+	}
+	if len(ids) != 10 {
+		t.Errorf("%d distinct message IDs in 10 messages", len(ids))
+	}
+}
+
+func TestSendTCPFraming(t *testing.T) {
+	doc, err := marshalGELF(map[string]interface{}{"short_message": "one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := marshalGELF(map[string]interface{}{"short_message": "two"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spooled := []byte(string(doc) + "\n" + string(other) + "\n")
+	line, rest, _ := bytes.Cut(spooled, []byte{'\n'})
+	foreign := append(make([]byte, 0, 64), doc...)
+	foreign = append(foreign, 'x')[:len(doc)] // spare capacity not ending in a newline
+
+	tests := []struct {
+		name      string
+		data      []byte
+		delimiter byte
+		want      string
+		inPlace   bool // written from data's own memory, without a copy
+	}{
+		{"message, newline", doc, '\n', string(doc) + "\n", true},
+		{"message, null", doc, 0, string(doc) + "\x00", false},
+		{"batch, newline", bytes.Join([][]byte{doc, other}, []byte{'\n'}), '\n', string(doc) + "\n" + string(other) + "\n", false},
+		{"batch, null", bytes.Join([][]byte{doc, other}, []byte{0}), 0, string(doc) + "\x00" + string(other) + "\x00", false},
+		{"spooled line, newline", line, '\n', string(doc) + "\n", true},
+		{"spooled line, null", line, 0, string(doc) + "\x00", false},
+		{"foreign capacity, newline", foreign, '\n', string(doc) + "\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := append([]byte(nil), tt.data[:cap(tt.data)]...)
+			conn := &recordConn{}
+			if err := sendTCP(conn, tt.data, tt.delimiter); err != nil {
+				t.Fatal(err)
+			}
+			if len(conn.writes) != 1 {
+				t.Fatalf("%d writes, want 1", len(conn.writes))
+			}
+			written := conn.writes[0]
+			if string(written) != tt.want {
+				t.Errorf("wrote %q, want %q", written, tt.want)
+			}
+			if inPlace := &written[0] == &tt.data[0]; inPlace != tt.inPlace {
+				t.Errorf("in place = %v, want %v", inPlace, tt.inPlace)
+			}
+			// Framing must never write into memory past data, which may
+			// belong to someone else
+			if !bytes.Equal(tt.data[:cap(tt.data)], before) {
+				t.Error("data's spare capacity was modified")
+			}
+		})
+	}
+	if string(rest) != string(other)+"\n" {
+		t.Errorf("spool's next line is now %q", rest)
+	}
+}
+
+func TestDryRunTCPDelimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		delimiter byte
+	}{
+		{"newline", nil, '\n'},
+		{"null", []Option{WithNullDelimiter()}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var frames [][]byte
+			opts := append([]Option{
+				WithGraylog("127.0.0.1", "12201", "tcp"),
+				WithDryRun(func(_, _ string, f [][]byte) { frames = append(frames, f...) }),
+				WithDisabledIPLookup(),
+				WithDisabledLocal(),
+			}, tt.opts...)
+			l, err := NewLoggerWithOptions(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Info("hello", LogData{}); err != nil {
+				t.Fatal(err)
+			}
+			if len(frames) != 1 {
+				t.Fatalf("%d frames, want 1", len(frames))
+			}
+			frame := frames[0]
+			if frame[len(frame)-1] != tt.delimiter || bytes.Count(frame, []byte{tt.delimiter}) != 1 {
+				t.Errorf("frame %q, want a single trailing %q", frame, tt.delimiter)
+			}
+		})
+	}
+}
+
+func BenchmarkSendTCP(b *testing.B) {
+	doc, err := marshalGELF(testDoc())
+	if err != nil {
+		b.Fatal(err)
+	}
+	batch := bytes.Join([][]byte{doc, doc, doc}, []byte{'\n'})
+	benchmarks := []struct {
+		name      string
+		data      []byte
+		delimiter byte
+	}{
+		{"message", doc, '\n'},
+		{"message null", doc, 0},
+		{"batch", batch, '\n'},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			conn := &recordConn{}
+			b.ReportAllocs()
+			for range b.N {
+				conn.writes = conn.writes[:0]
+				if err := sendTCP(conn, bm.data, bm.delimiter); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// NewTransactionID returns a random version 4 UUID for use as a
+// LogData.TransactionID
+func NewTransactionID() string {
+	var b [16]byte
+	rand.Read(b[:])         // never fails on supported platforms
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// TransactionID returns the transaction ID preset by WithFields, which
+// WithTransactionIDs generates if none was given, so it can be propagated
+// to other services
+func (l *Logger) TransactionID() string {
+	return l.fields.TransactionID
+}
@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationError lists what is wrong with a message rejected by Validate
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "graylog: invalid message: " + strings.Join(e.Problems, "; ")
+}
+
+// validate checks doc against GELF's rules and the fields Graylog
+// pipelines rely on, returning a *ValidationError listing every problem
+func (l *Logger) validate(doc map[string]interface{}) error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if doc["version"] != gelfVersion {
+		addf("version is %v, not %s", doc["version"], gelfVersion)
+	}
+	for _, key := range []string{"host", "short_message", "_appname"} {
+		if s, _ := doc[key].(string); strings.TrimSpace(s) == "" {
+			addf("%s is empty", key)
+		}
+	}
+
+	switch ts := doc["timestamp"].(type) {
+	case float64:
+		if ts <= 0 {
+			addf("timestamp %v is not a Unix time", ts)
+		}
+	case string:
+		if _, err := time.Parse(l.TimestampFormat, ts); err != nil {
+			addf("timestamp %q does not parse as %q", ts, l.TimestampFormat)
+		}
+	default:
+		addf("timestamp is missing")
+	}
+	if level, ok := doc["level"].(int); !ok || level < 0 || level > 7 {
+		addf("level %v is not a syslog severity", doc["level"])
+	}
+
+	for key, value := range doc {
+		if !strings.HasPrefix(key, "_") {
+			continue
+		}
+		if key == "_id" || key != "_"+fieldName(key) {
+			addf("field name %q is not allowed", key)
+		}
+		switch value.(type) {
+		case string, bool, json.Number, float32, float64,
+			int, int8, int16, int32, int64,
+			uint, uint8, uint16, uint32, uint64:
+		default:
+			addf("field %s has type %T, not a string or number", key, value)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems) // doc's iteration order is random
+	return &ValidationError{Problems: problems}
+}
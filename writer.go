@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"io"
+	"strings"
+)
+
+// levelWriter is an io.Writer that logs each Write as one message
+type levelWriter struct {
+	logger *Logger
+	level  Level
+}
+
+// Writer returns an io.Writer that logs every Write as a single message at
+// level, e.g. log.SetOutput(l.Writer(LevelInfo)). A trailing newline is
+// trimmed from the message.
+func (l *Logger) Writer(level Level) io.Writer {
+	return &levelWriter{logger: l, level: level}
+}
+
+// Write logs p. The local write always happens, so the full length is
+// reported even when the Graylog send fails.
+func (w *levelWriter) Write(p []byte) (int, error) {
+	message := strings.TrimSuffix(string(p), "\n")
+	return len(p), w.logger.Log(w.level, message, LogData{})
+}